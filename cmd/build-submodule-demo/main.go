@@ -16,6 +16,7 @@ import (
 	"github.com/upbound/build-submodule-demo/internal/runtime"
 	"github.com/upbound/build-submodule-demo/internal/server/api"
 	"github.com/upbound/build-submodule-demo/internal/server/metrics"
+	"github.com/upbound/build-submodule-demo/internal/server/middleware"
 	"github.com/upbound/build-submodule-demo/internal/server/private"
 )
 
@@ -54,23 +55,69 @@ func run(opts internal.ServiceOptions) error { // nolint: gocyclo
 	g, ctx := errgroup.WithContext(context.Background())
 	done := make(chan struct{})
 
+	// The adaptive limiter is shared by the API and private servers so that
+	// private traffic takes precedence when the process is overloaded.
+	var limiter *middleware.AdaptiveLimiter
+	if opts.AdaptiveLimit {
+		limiter = middleware.NewAdaptiveLimiter(
+			middleware.AdaptiveWithLimits(opts.AdaptiveLimitInitial, opts.AdaptiveLimitMin, opts.AdaptiveLimitMax),
+			middleware.AdaptiveWithTargetLatency(opts.AdaptiveLimitTargetLatency),
+			middleware.AdaptiveWithBackoff(opts.AdaptiveLimitBackoff),
+			middleware.AdaptiveWithRetryAfter(opts.ThrottleRetryAfter),
+			middleware.AdaptiveWithPrioritizedPaths(opts.AdaptiveLimitPrioritizedPaths...),
+		)
+	}
+
 	if opts.API {
-		apiServer, err := api.Server(opts)
+		cors, err := middleware.NewCORS(
+			middleware.CORSWithLogger(opts.Log),
+			middleware.CORSWithOrigins(opts.CORSAllowedOrigins...),
+			middleware.CORSWithMethods(opts.CORSAllowedMethods...),
+			middleware.CORSWithHeaders(opts.CORSAllowedHeaders...),
+			middleware.CORSWithExposedHeaders(opts.CORSExposedHeaders...),
+			middleware.CORSWithCredentials(opts.CORSAllowCredentials),
+			middleware.CORSWithMaxAge(opts.CORSMaxAge),
+		)
 		if err != nil {
 			return err
 		}
-		runtime.StartServer(apiServer, g, done, runtime.WithLogger(opts.Log), runtime.WithName("api"))
+		if opts.CORSOriginsFile != "" {
+			if err := cors.LoadOrigins(opts.CORSOriginsFile); err != nil {
+				return err
+			}
+			reloadOrigins(cors, opts, done)
+		}
+		apiServer, err := api.Server(opts, limiter, cors)
+		if err != nil {
+			return err
+		}
+		runtime.StartServer(apiServer, g, done, runtime.WithLogger(opts.Log), runtime.WithName("api"), runtime.WithMaxConnections(opts.MaxConnections))
 	}
 
 	if opts.Metrics {
-		metricsServer, err := metrics.Server(opts.MetricsOptions, opts.Log)
+		// The meter provider is shut down after the signal to stop so that
+		// pending metrics are flushed to the configured exporters.
+		provider, err := metrics.NewMeterProvider(ctx, opts.MetricsOptions)
 		if err != nil {
 			return err
 		}
-		runtime.StartServer(metricsServer, g, done, runtime.WithLogger(opts.Log), runtime.WithName("metrics"))
+		runtime.StartUpDown(nil, provider.Shutdown, g, done, runtime.WithLogger(opts.Log), runtime.WithName("meter-provider"))
+
+		// The tracer provider links recorded metrics to propagated traces
+		// through exemplars.
+		tracer := metrics.NewTracerProvider()
+		runtime.StartUpDown(nil, tracer.Shutdown, g, done, runtime.WithLogger(opts.Log), runtime.WithName("tracer-provider"))
+
+		if metrics.PrometheusEnabled(opts.MetricsOptions) {
+			metricsServer, err := metrics.Server(opts.CommonOptions)
+			if err != nil {
+				return err
+			}
+			runtime.StartServer(metricsServer, g, done, runtime.WithLogger(opts.Log), runtime.WithName("metrics"))
+		}
 	}
 
-	privateServer, err := private.Server(opts)
+	privateServer, err := private.Server(opts, limiter)
 	if err != nil {
 		return err
 	}
@@ -91,3 +138,23 @@ func run(opts internal.ServiceOptions) error { // nolint: gocyclo
 	}()
 	return g.Wait()
 }
+
+// reloadOrigins reloads the allowed CORS origins from the configured file on
+// SIGHUP until done is closed.
+func reloadOrigins(cors *middleware.CORS, opts internal.ServiceOptions, done <-chan struct{}) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(sighup)
+		for {
+			select {
+			case <-sighup:
+				if err := cors.LoadOrigins(opts.CORSOriginsFile); err != nil {
+					opts.Log.Info("Failed to reload CORS origins.", "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+}
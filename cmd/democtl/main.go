@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/upbound/build-submodule-demo/internal/client/demo"
+)
+
+// cli is the democtl command line.
+type cli struct {
+	Server  string        `default:"http://localhost:8081" env:"DEMOCTL_SERVER" help:"URL of the API server."`
+	Token   string        `env:"DEMOCTL_TOKEN" help:"Bearer token to authenticate with."`
+	Session string        `env:"DEMOCTL_SESSION" help:"Session cookie to authenticate with, if no token is set."`
+	Retries int           `default:"3" help:"Maximum number of retries of transient failures."`
+	Timeout time.Duration `default:"30s" help:"Timeout of each command, including retries."`
+
+	Demo     demoCmd     `cmd:"" help:"Call the demo endpoint and print its response."`
+	Liveness livenessCmd `cmd:"" help:"Check that the service is live. Use the private server URL."`
+	Ready    readyCmd    `cmd:"" help:"Check that the service is ready. Use the private server URL."`
+}
+
+type demoCmd struct{}
+
+func (c *demoCmd) Run(ctx context.Context, client *demo.Client) error {
+	b, err := client.GetV1Demo(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(b)
+	return err
+}
+
+type livenessCmd struct{}
+
+func (c *livenessCmd) Run(ctx context.Context, client *demo.Client) error {
+	return client.GetLiveness(ctx)
+}
+
+type readyCmd struct{}
+
+func (c *readyCmd) Run(ctx context.Context, client *demo.Client) error {
+	return client.GetReadiness(ctx)
+}
+
+func main() {
+	opts := cli{}
+	kctx := kong.Parse(&opts, kong.Name("democtl"),
+		kong.Description("Call the build-submodule-demo API from scripts."),
+		kong.UsageOnError(),
+		kong.ConfigureHelp(kong.HelpOptions{
+			Compact: true,
+			Summary: true,
+		}))
+
+	client, err := demo.New(opts.Server,
+		demo.WithBearerToken(opts.Token),
+		demo.WithSessionCookie(opts.Session),
+		demo.WithRetries(opts.Retries),
+	)
+	kctx.FatalIfErrorf(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+	kctx.BindTo(ctx, (*context.Context)(nil))
+	if err := kctx.Run(client); err != nil {
+		fmt.Fprintln(os.Stderr, "democtl:", err)
+		cancel()
+		os.Exit(1)
+	}
+}
@@ -1,11 +1,12 @@
 // Package api provides primitives to interact with the openapi HTTP API.
 //
-// Code generated by github.com/deepmap/oapi-codegen version v1.11.0 DO NOT EDIT.
+// Code generated by github.com/deepmap/oapi-codegen version v1.12.4 DO NOT EDIT.
 package api
 
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"net/http"
@@ -31,21 +32,21 @@ type ServerInterfaceWrapper struct {
 	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
 }
 
-type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
+type MiddlewareFunc func(http.Handler) http.Handler
 
 // GetV1Demo operation middleware
 func (siw *ServerInterfaceWrapper) GetV1Demo(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	var handler = func(w http.ResponseWriter, r *http.Request) {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.GetV1Demo(w, r)
-	}
+	})
 
 	for _, middleware := range siw.HandlerMiddlewares {
 		handler = middleware(handler)
 	}
 
-	handler(w, r.WithContext(ctx))
+	handler.ServeHTTP(w, r.WithContext(ctx))
 }
 
 type UnescapedCookieParamError struct {
@@ -61,16 +62,16 @@ func (e *UnescapedCookieParamError) Unwrap() error {
 	return e.Err
 }
 
-type UnmarshalingParamError struct {
+type UnmarshallingParamError struct {
 	ParamName string
 	Err       error
 }
 
-func (e *UnmarshalingParamError) Error() string {
-	return fmt.Sprintf("Error unmarshaling parameter %s as JSON: %s", e.ParamName, e.Err.Error())
+func (e *UnmarshallingParamError) Error() string {
+	return fmt.Sprintf("Error unmarshalling parameter %s as JSON: %s", e.ParamName, e.Err.Error())
 }
 
-func (e *UnmarshalingParamError) Unwrap() error {
+func (e *UnmarshallingParamError) Unwrap() error {
 	return e.Err
 }
 
@@ -168,13 +169,92 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 	return r
 }
 
+type GetV1DemoRequestObject struct {
+}
+
+type GetV1DemoResponseObject interface {
+	VisitGetV1DemoResponse(w http.ResponseWriter) error
+}
+
+type GetV1Demo200TextResponse string
+
+func (response GetV1Demo200TextResponse) VisitGetV1DemoResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(200)
+
+	_, err := w.Write([]byte(response))
+	return err
+}
+
+// StrictServerInterface represents all server handlers.
+type StrictServerInterface interface {
+	// Demo path.
+	// (GET /v1/demo)
+	GetV1Demo(ctx context.Context, request GetV1DemoRequestObject) (GetV1DemoResponseObject, error)
+}
+
+type StrictHandlerFunc func(ctx context.Context, w http.ResponseWriter, r *http.Request, args interface{}) (interface{}, error)
+
+type StrictMiddlewareFunc func(f StrictHandlerFunc, operationID string) StrictHandlerFunc
+
+type StrictHTTPServerOptions struct {
+	RequestErrorHandlerFunc  func(w http.ResponseWriter, r *http.Request, err error)
+	ResponseErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func NewStrictHandler(ssi StrictServerInterface, middlewares []StrictMiddlewareFunc) ServerInterface {
+	return &strictHandler{ssi: ssi, middlewares: middlewares, options: StrictHTTPServerOptions{
+		RequestErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		},
+		ResponseErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		},
+	}}
+}
+
+func NewStrictHandlerWithOptions(ssi StrictServerInterface, middlewares []StrictMiddlewareFunc, options StrictHTTPServerOptions) ServerInterface {
+	return &strictHandler{ssi: ssi, middlewares: middlewares, options: options}
+}
+
+type strictHandler struct {
+	ssi         StrictServerInterface
+	middlewares []StrictMiddlewareFunc
+	options     StrictHTTPServerOptions
+}
+
+// GetV1Demo operation middleware
+func (sh *strictHandler) GetV1Demo(w http.ResponseWriter, r *http.Request) {
+	var request GetV1DemoRequestObject
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.GetV1Demo(ctx, request.(GetV1DemoRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "GetV1Demo")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(GetV1DemoResponseObject); ok {
+		if err := validResponse.VisitGetV1DemoResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("Unexpected response type: %T", response))
+	}
+}
+
 // Base64 encoded, gzipped, json marshaled Swagger object
 var swaggerSpec = []string{
 
-	"H4sIAAAAAAAC/2SOMWvDMBBG/4r5ZuHY7aaphZoSOrQ0pbtqX9yD6CSksyEE/fciN0vIJr4nPb0LxuBj",
-	"EBLNsJdiwHIMsHUXdaPWI3nHJ1iQzCxEiWV+WuJPWGRqOcBAnCdYDFeOYjBRHhNH5SCw+BwOX80gUwws",
-	"mptjSM0L+dA8f+xhoKyn+v5AaeWRmkGU9XyFK6X8L+nbrppDJHGRYfHY1skgOv2t9dit/W4iv/XPtLXf",
-	"dmyf1ustNlFyFewnWLySfveVwyBRjkEybdKHrrsXvb+hFIO8eO/S+dZcSil/AQAA//+xJb7dWAEAAA==",
+	"H4sIAAAAAAAC/2SQQUsDMRCF/8ryzsu21VtOCi5SPChWvMfdaTvQTEIyLZaS/y4T66F4S95LvnyZC6YY",
+	"UhQSLXCX2oNlG+EsF/WT2pKC5wMcSHYsRJll93BMX/Eo88ARPcQHgsN47VF7zFSmzEk5Chzex81HN8qc",
+	"IouWbhtz90Qhdo9va/RQ1oPd31A+8UTdKMp6vpYnyuUXshqWRo6JxCeGw/1gUY/kdW/2WJxWi5lC899R",
+	"c7/1aI/a8QENlL0V6xkOz6SfK+vRI1NJUQo16N1y+TcPksZU+tZFOngW25VpT8G3/JzsG0VtQqj13xhe",
+	"X2BhOYbg8/nWp9ZafwYAuwzg4o4BAAA=",
 }
 
 // GetSwagger returns the content of the embedded swagger specification file
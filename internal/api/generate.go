@@ -16,9 +16,14 @@
 // limitations under the License.
 
 // Generate OpenAPI server stubs
-//go:generate go run -tags generate github.com/deepmap/oapi-codegen/cmd/oapi-codegen -old-config-style -generate types,chi-server,spec -o health/server.gen.go -package api health.yaml
+//go:generate go run -tags generate github.com/deepmap/oapi-codegen/cmd/oapi-codegen -old-config-style -generate types,chi-server,strict-server,spec -o health/server.gen.go -package api health.yaml
 
-//go:generate go run -tags generate github.com/deepmap/oapi-codegen/cmd/oapi-codegen -old-config-style -generate types,chi-server,spec -o demo/server.gen.go -package api demo.yaml
+//go:generate go run -tags generate github.com/deepmap/oapi-codegen/cmd/oapi-codegen -old-config-style -generate types,chi-server,strict-server,spec -o demo/server.gen.go -package api demo.yaml
+
+// Generate OpenAPI clients
+//go:generate go run -tags generate github.com/deepmap/oapi-codegen/cmd/oapi-codegen -old-config-style -generate client -o health/client.gen.go -package api health.yaml
+
+//go:generate go run -tags generate github.com/deepmap/oapi-codegen/cmd/oapi-codegen -old-config-style -generate client -o demo/client.gen.go -package api demo.yaml
 
 package api
 
@@ -122,7 +122,7 @@ func New(authHost, privateHost url.URL, opts ...ClientOpt) *ExternalClient {
 			Timeout: 10 * time.Second,
 			// TODO(hasheddan): consider passing base transport with more
 			// granular timeouts.
-			Transport: otelhttp.NewTransport(nil),
+			Transport: otelhttp.NewTransport(&shttp.RequestIDTransport{}),
 		},
 	}
 	for _, o := range opts {
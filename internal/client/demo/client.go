@@ -0,0 +1,166 @@
+// Package demo is a client for the demo and health APIs, built on the
+// generated OpenAPI clients. It authenticates requests, retries them when it
+// is safe to, and decodes problem details into errors.
+package demo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	apidemo "github.com/upbound/build-submodule-demo/internal/api/demo"
+	healthapi "github.com/upbound/build-submodule-demo/internal/api/health"
+	"github.com/upbound/build-submodule-demo/internal/client/auth"
+	shttp "github.com/upbound/build-submodule-demo/internal/client/http"
+	"github.com/upbound/build-submodule-demo/internal/protocol"
+)
+
+const (
+	errNewClient  = "failed to create client"
+	errReadBody   = "failed to read response body"
+	errCSRFToken  = "failed to generate CSRF token"
+	errCallFailed = "request failed"
+)
+
+// Client calls the demo and health APIs.
+type Client struct {
+	client  shttp.Client
+	token   string
+	session string
+	retry   retryPolicy
+
+	demo   *apidemo.Client
+	health *healthapi.Client
+}
+
+// Opt modifies a client.
+type Opt func(c *Client)
+
+// WithHTTPClient sets the HTTP client used to make requests. Request IDs are
+// propagated from the request context by default.
+func WithHTTPClient(hc shttp.Client) Opt {
+	return func(c *Client) {
+		c.client = hc
+	}
+}
+
+// WithBearerToken authenticates requests with the supplied bearer token.
+func WithBearerToken(token string) Opt {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithSessionCookie authenticates requests with the supplied session cookie.
+// Unsafe requests are sent with a double-submit CSRF token.
+func WithSessionCookie(session string) Opt {
+	return func(c *Client) {
+		c.session = session
+	}
+}
+
+// WithRetries sets the maximum number of times a request is retried. Only
+// requests that are idempotent, or carry an Idempotency-Key, are retried.
+func WithRetries(n int) Opt {
+	return func(c *Client) {
+		c.retry.max = n
+	}
+}
+
+// WithRetryBackoff sets the delay before the first retry, which doubles for
+// each subsequent retry up to the supplied maximum. Delays requested by the
+// server with Retry-After are honored up to the maximum.
+func WithRetryBackoff(initial, max time.Duration) Opt {
+	return func(c *Client) {
+		c.retry.initial = initial
+		c.retry.maxDelay = max
+	}
+}
+
+// New constructs a client for the API served at the supplied URL, e.g.
+// https://api.example.com.
+func New(server string, opts ...Opt) (*Client, error) {
+	c := &Client{
+		client: &http.Client{Transport: &shttp.RequestIDTransport{}},
+		retry: retryPolicy{
+			max:      3,
+			initial:  200 * time.Millisecond,
+			maxDelay: 10 * time.Second,
+		},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	doer := &retryDoer{client: c.client, policy: c.retry}
+	var err error
+	if c.demo, err = apidemo.NewClient(server, apidemo.WithHTTPClient(doer), apidemo.WithRequestEditorFn(c.authenticate)); err != nil {
+		return nil, errors.Wrap(err, errNewClient)
+	}
+	if c.health, err = healthapi.NewClient(server, healthapi.WithHTTPClient(doer), healthapi.WithRequestEditorFn(c.authenticate)); err != nil {
+		return nil, errors.Wrap(err, errNewClient)
+	}
+	return c, nil
+}
+
+// GetV1Demo calls the demo endpoint and returns its response body.
+func (c *Client) GetV1Demo(ctx context.Context) ([]byte, error) {
+	return body(c.demo.GetV1Demo(ctx))
+}
+
+// GetLiveness returns an error if the service is not live.
+func (c *Client) GetLiveness(ctx context.Context) error {
+	_, err := body(c.health.GetLiveness(ctx))
+	return err
+}
+
+// GetReadiness returns an error if the service is not ready.
+func (c *Client) GetReadiness(ctx context.Context) error {
+	_, err := body(c.health.GetReadiness(ctx))
+	return err
+}
+
+// authenticate adds the configured credentials to a request.
+func (c *Client) authenticate(_ context.Context, req *http.Request) error {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		return nil
+	}
+	if c.session == "" {
+		return nil
+	}
+	req.AddCookie(&http.Cookie{Name: auth.SessionCookieName, Value: c.session})
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return nil
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return errors.Wrap(err, errCSRFToken)
+	}
+	tok := base64.RawURLEncoding.EncodeToString(b)
+	req.AddCookie(&http.Cookie{Name: protocol.CSRFCookieName, Value: tok})
+	req.Header.Set(protocol.CSRFHeaderName, tok)
+	return nil
+}
+
+// body reads the body of a response, returning an *Error if it was not
+// successful.
+func body(res *http.Response, err error) ([]byte, error) {
+	if err != nil {
+		return nil, errors.Wrap(err, errCallFailed)
+	}
+	defer res.Body.Close() //nolint:errcheck
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadBody)
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return nil, NewError(res, b)
+	}
+	return b, nil
+}
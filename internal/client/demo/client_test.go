@@ -0,0 +1,127 @@
+package demo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/build-submodule-demo/internal/protocol"
+)
+
+func TestGetV1Demo(t *testing.T) {
+	type arguments struct {
+		opts     []Opt
+		statuses []int
+	}
+	type want struct {
+		body  string
+		err   string
+		calls int
+		auth  string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Success": {
+			reason: "The response body should be returned, authenticated with the bearer token.",
+			args: arguments{
+				opts:     []Opt{WithBearerToken("t0k3n")},
+				statuses: []int{http.StatusOK},
+			},
+			want: want{body: "Hello World!", calls: 1, auth: "Bearer t0k3n"},
+		},
+		"Retry": {
+			reason: "Transient failures should be retried.",
+			args:   arguments{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}},
+			want:   want{body: "Hello World!", calls: 3},
+		},
+		"RetriesExhausted": {
+			reason: "The last failure should be returned once retries are exhausted.",
+			args: arguments{
+				opts:     []Opt{WithRetries(1)},
+				statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+			},
+			want: want{err: "503 Service Unavailable: unavailable (request r1)", calls: 2},
+		},
+		"Problem": {
+			reason: "Problem details should be decoded into the returned error.",
+			args:   arguments{statuses: []int{http.StatusBadRequest}},
+			want:   want{err: "400 Bad Request: unavailable (request r1)", calls: 1},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tc.args.statuses[got.calls]
+				got.calls++
+				got.auth = r.Header.Get("Authorization")
+				if status >= http.StatusBadRequest {
+					w.Header().Set("Content-Type", protocol.ProblemContentType)
+					w.WriteHeader(status)
+					_ = json.NewEncoder(w).Encode(&protocol.Problem{Title: http.StatusText(status), Status: status, Detail: "unavailable", RequestID: "r1"})
+					return
+				}
+				_, _ = w.Write([]byte("Hello World!"))
+			}))
+			defer srv.Close()
+
+			c, err := New(srv.URL, append([]Opt{WithRetryBackoff(time.Millisecond, time.Millisecond)}, tc.args.opts...)...)
+			if err != nil {
+				t.Fatalf("New(...): %v", err)
+			}
+			b, err := c.GetV1Demo(context.Background())
+			got.body = string(b)
+			if err != nil {
+				got.err = err.Error()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nGetV1Demo(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		method string
+		key    string
+		want   bool
+	}{
+		"Get": {
+			reason: "Safe requests should be retryable.",
+			method: http.MethodGet,
+			want:   true,
+		},
+		"Post": {
+			reason: "Unsafe requests should not be retryable.",
+			method: http.MethodPost,
+			want:   false,
+		},
+		"PostIdempotencyKey": {
+			reason: "Unsafe requests with an idempotency key should be retryable.",
+			method: http.MethodPost,
+			key:    "k1",
+			want:   true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			if tc.key != "" {
+				req.Header.Set(protocol.HeaderIdempotencyKey, tc.key)
+			}
+			if diff := cmp.Diff(tc.want, retryable(req)); diff != "" {
+				t.Errorf("\n%s\nretryable(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal/protocol"
+)
+
+// An Error is an unsuccessful response from the API. Problem details are
+// decoded from application/problem+json responses; other responses are
+// described by their status and body.
+type Error struct {
+	protocol.Problem
+}
+
+// NewError returns an error describing an unsuccessful response with the
+// supplied body.
+func NewError(res *http.Response, body []byte) *Error {
+	e := &Error{}
+	if mt, _, err := mime.ParseMediaType(res.Header.Get("Content-Type")); err == nil && mt == protocol.ProblemContentType {
+		_ = json.Unmarshal(body, &e.Problem)
+	}
+	if e.Status == 0 {
+		e.Status = res.StatusCode
+	}
+	if e.Title == "" {
+		e.Title = http.StatusText(res.StatusCode)
+	}
+	if e.Detail == "" && e.Type == "" {
+		e.Detail = strings.TrimSpace(string(body))
+	}
+	if e.RequestID == "" {
+		e.RequestID = res.Header.Get("X-Request-ID")
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d %s", e.Status, e.Title)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	for _, fe := range e.Errors {
+		msg += fmt.Sprintf("; %s %s: %s", fe.In, fe.Field, fe.Reason)
+	}
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// IsStatus reports whether err is an *Error with the supplied status.
+func IsStatus(err error, status int) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Status == status
+}
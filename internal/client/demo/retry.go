@@ -0,0 +1,109 @@
+package demo
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	shttp "github.com/upbound/build-submodule-demo/internal/client/http"
+	"github.com/upbound/build-submodule-demo/internal/protocol"
+)
+
+// retryPolicy determines how requests are retried.
+type retryPolicy struct {
+	max      int
+	initial  time.Duration
+	maxDelay time.Duration
+}
+
+// retryDoer retries requests that failed transiently.
+type retryDoer struct {
+	client shttp.Client
+	policy retryPolicy
+}
+
+// retryStatuses are the response statuses that are retried.
+var retryStatuses = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// Do makes a request, retrying it after connection errors and transient
+// failure statuses if it is safe to repeat.
+func (d *retryDoer) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := d.client.Do(req)
+		if attempt >= d.policy.max || !retryable(req) || (err == nil && !retryStatuses[res.StatusCode]) {
+			return res, err
+		}
+		delay := d.policy.delay(attempt)
+		if err == nil {
+			if ra, ok := retryAfter(res); ok {
+				delay = min(ra, d.policy.maxDelay)
+			}
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		case <-t.C:
+		}
+	}
+}
+
+// delay returns the exponential backoff before a retry, with full jitter.
+func (p retryPolicy) delay(attempt int) time.Duration {
+	d := p.initial << attempt
+	if d <= 0 || d > p.maxDelay {
+		d = p.maxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d))) //nolint:gosec // Jitter need not be cryptographically random.
+}
+
+// retryable reports whether a request may be repeated without side effects.
+func retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(protocol.HeaderIdempotencyKey) != ""
+}
+
+// rewind returns a copy of a request with its body reset.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+	b, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Body = b
+	return r, nil
+}
+
+// retryAfter returns the delay requested by a Retry-After header in seconds.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	s, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || s < 0 {
+		return 0, false
+	}
+	return time.Duration(s) * time.Second, true
+}
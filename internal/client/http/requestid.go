@@ -0,0 +1,34 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestIDHeader is the header used to propagate request IDs between
+// services.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDTransport forwards the request ID found in the request context to
+// upstream services.
+type RequestIDTransport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip sets the request ID header, if an ID is present in the request
+// context, and executes the request with the base transport.
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	id := middleware.GetReqID(req.Context())
+	if id == "" || req.Header.Get(RequestIDHeader) != "" {
+		return base.RoundTrip(req)
+	}
+	// A RoundTripper must not modify the supplied request.
+	req = req.Clone(req.Context())
+	req.Header.Set(RequestIDHeader, id)
+	return base.RoundTrip(req)
+}
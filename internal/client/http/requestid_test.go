@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/go-cmp/cmp"
+)
+
+// roundTripFunc is a RoundTripper implemented by a function.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	type arguments struct {
+		id     string
+		header string
+	}
+	type want struct {
+		sent     string
+		original string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"NoID": {
+			reason: "Requests without an ID in their context should be sent as is.",
+			args:   arguments{},
+			want:   want{},
+		},
+		"ID": {
+			reason: "The ID in the request context should be sent without modifying the supplied request.",
+			args:   arguments{id: "abc"},
+			want:   want{sent: "abc"},
+		},
+		"Header": {
+			reason: "An ID already set on the request should not be replaced.",
+			args:   arguments{id: "abc", header: "def"},
+			want:   want{sent: "def", original: "def"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			rt := &RequestIDTransport{Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				got.sent = req.Header.Get(RequestIDHeader)
+				return httptest.NewRecorder().Result(), nil
+			})}
+			ctx := context.Background()
+			if tc.args.id != "" {
+				ctx = context.WithValue(ctx, middleware.RequestIDKey, tc.args.id)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+			if tc.args.header != "" {
+				req.Header.Set(RequestIDHeader, tc.args.header)
+			}
+			res, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip(...): %v", err)
+			}
+			res.Body.Close()
+			got.original = req.Header.Get(RequestIDHeader)
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nRoundTrip(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
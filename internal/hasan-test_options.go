@@ -2,6 +2,7 @@ package internal
 
 import (
 	"net/url"
+	"time"
 )
 
 // ServiceOptions defines the available set of configuration options available
@@ -13,5 +14,84 @@ type ServiceOptions struct {
 	AuthHost    url.URL `default:"http://api-private-auth:8081" help:"Auth build-submodule-demo host."`
 	PrivateHost url.URL `default:"http://api-private:8081" help:"Private build-submodule-demo host."`
 
+	CSRF bool `name:"csrf" default:"true" negatable:"" help:"Protect cookie-authenticated, state-changing API requests from cross-site request forgery."`
+
+	PathPrefix        string   `help:"Path prefix the API is exposed under by the ingress, used in response URLs when X-Forwarded-Prefix is not set."`
+	RewriteJSONFields []string `help:"Dot separated paths of JSON response fields holding URLs to rewrite for the client, e.g. next,links.self."`
+
+	RequestDecompression       bool    `default:"true" negatable:"" help:"Accept gzip, zstd and brotli encoded request bodies."`
+	RequestMaxDecompressedSize int64   `default:"33554432" help:"Maximum decompressed size in bytes of request bodies."`
+	RequestMaxCompressionRatio float64 `default:"100" help:"Maximum ratio of decompressed to compressed size of request bodies."`
+
+	APITimeouts TimeoutOptions `embed:"" prefix:"api-" envprefix:"API_"`
+
+	ETags bool `name:"etags" default:"true" negatable:"" help:"Set ETags on API responses and answer conditional requests."`
+
+	Idempotency            bool          `default:"true" negatable:"" help:"Replay responses to unsafe API requests retried with the same Idempotency-Key."`
+	IdempotencyTTL         time.Duration `default:"24h" help:"How long responses to requests with an Idempotency-Key are replayed for."`
+	IdempotencyLockTimeout time.Duration `default:"1m" help:"How long an Idempotency-Key is held by a request that does not complete."`
+
+	ThrottleOptions
+	RateLimitOptions
+	AdaptiveLimitOptions
+	CORSOptions
+	SecurityHeadersOptions
 	CommonOptions
 }
+
+// ThrottleOptions defines the options for limiting concurrent requests and
+// connections to the API.
+type ThrottleOptions struct {
+	ThrottleLimit          int            `default:"400" help:"Maximum number of API requests processed concurrently."`
+	ThrottleBacklogLimit   int            `default:"0" help:"Maximum number of API requests waiting for a slot once the limit is reached."`
+	ThrottleBacklogTimeout time.Duration  `default:"30s" help:"Maximum time an API request waits in the backlog."`
+	ThrottleRetryAfter     time.Duration  `default:"1s" help:"Retry-After duration returned to throttled callers."`
+	ThrottleRouteLimits    map[string]int `help:"Per route group concurrency limits, e.g. demo=100."`
+	ThrottleExemptPaths    []string       `help:"API paths that are never throttled, e.g. /v1/demo."`
+	MaxConnections         int            `default:"0" help:"Maximum open connections on the API listener. 0 is unlimited."`
+}
+
+// RateLimitOptions defines the options for per caller rate limiting of the
+// API. Limits are given as <rate per second>/<burst>.
+type RateLimitOptions struct {
+	RateLimit         bool              `default:"false" negatable:"" help:"Enable per caller rate limiting of the API."`
+	RateLimitDefault  string            `default:"10/20" help:"Default per caller rate limit."`
+	RateLimitEntities map[string]string `help:"Per entity type rate limits, e.g. robot=5/10;anonymous=1/5."`
+	RateLimitRoutes   map[string]string `help:"Per route rate limits keyed by route pattern, optionally prefixed by entity type, e.g. /v1/demo=2/4;robot:/v1/demo=1/2."`
+}
+
+// AdaptiveLimitOptions defines the options for adaptive concurrency limiting
+// of the API based on observed request latency.
+type AdaptiveLimitOptions struct {
+	AdaptiveLimit                 bool          `default:"false" negatable:"" help:"Enable adaptive concurrency limiting of the API."`
+	AdaptiveLimitInitial          int           `default:"100" help:"Initial adaptive concurrency limit."`
+	AdaptiveLimitMin              int           `default:"10" help:"Minimum adaptive concurrency limit."`
+	AdaptiveLimitMax              int           `default:"1000" help:"Maximum adaptive concurrency limit."`
+	AdaptiveLimitTargetLatency    time.Duration `default:"250ms" help:"Request latency above which the concurrency limit is decreased."`
+	AdaptiveLimitBackoff          float64       `default:"0.9" help:"Factor the concurrency limit is multiplied by when requests are slow."`
+	AdaptiveLimitPrioritizedPaths []string      `default:"/livez,/readyz" help:"Paths that are never shed."`
+}
+
+// CORSOptions defines the Cross-Origin Resource Sharing policy of the API.
+type CORSOptions struct {
+	CORSAllowedOrigins   []string      `help:"Origins allowed to call the API, e.g. https://console.example.com or https://*.example.com."`
+	CORSOriginsFile      string        `help:"File of allowed origins, one per line, that replaces the allowed origins and is reloaded on SIGHUP."`
+	CORSAllowedMethods   []string      `default:"GET,HEAD,POST,PUT,PATCH,DELETE" help:"Methods allowed in cross-origin requests."`
+	CORSAllowedHeaders   []string      `default:"Authorization,Content-Type,X-Request-ID,X-CSRF-Token,Idempotency-Key,If-Match,If-None-Match" help:"Request headers allowed in cross-origin requests."`
+	CORSExposedHeaders   []string      `default:"X-Request-ID,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Idempotent-Replayed,ETag" help:"Response headers exposed to cross-origin callers."`
+	CORSAllowCredentials bool          `default:"true" negatable:"" help:"Allow cross-origin requests with credentials."`
+	CORSMaxAge           time.Duration `default:"10m" help:"How long preflight responses may be cached."`
+}
+
+// SecurityHeadersOptions defines the hardening headers set on API responses.
+// Operations may override them with the x-security-headers OpenAPI extension.
+type SecurityHeadersOptions struct {
+	SecurityHeaders       bool          `default:"true" negatable:"" help:"Set security headers on API responses."`
+	HSTSMaxAge            time.Duration `default:"8760h" help:"Max age of Strict-Transport-Security, sent to https clients. 0 disables HSTS."`
+	HSTSIncludeSubdomains bool          `default:"true" negatable:"" help:"Apply HSTS to subdomains."`
+	HSTSPreload           bool          `default:"false" negatable:"" help:"Allow the domain to be preloaded as HSTS only by browsers."`
+	ReferrerPolicy        string        `default:"no-referrer" help:"Referrer-Policy of API responses. Empty omits the header."`
+	FrameOptions          string        `default:"DENY" help:"X-Frame-Options of API responses. Empty omits the header."`
+	ContentSecurityPolicy string        `default:"frame-ancestors 'none'" help:"Content-Security-Policy of API responses. Empty omits the header."`
+	NoStoreAuthenticated  bool          `default:"true" negatable:"" help:"Mark responses to authenticated requests as not to be cached."`
+}
@@ -0,0 +1,52 @@
+// Package idempotency stores the responses to requests made with an
+// idempotency key, so that retried requests are replayed rather than
+// repeated.
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// A Response is a stored response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// A Record is the state of a request made with an idempotency key.
+type Record struct {
+	// Fingerprint identifies the request the key was first used with.
+	Fingerprint string
+
+	// Owner identifies the request holding the key. It is only returned to
+	// the request that began it, which must supply it to complete or abort
+	// the request.
+	Owner string
+
+	// Response is the response to the request, or nil while it is in
+	// flight.
+	Response *Response
+}
+
+// A Store holds records by key. Implementations backed by a shared store,
+// such as Redis, allow requests to be replayed across replicas and must begin
+// requests atomically.
+type Store interface {
+	// Begin records that a request with the supplied key and fingerprint is
+	// in flight, and returns true with a record naming its owner. If the key
+	// is already recorded the existing record is returned instead. The in
+	// flight record expires after the supplied lock timeout, so that a key
+	// is not held forever by a request that never completes.
+	Begin(ctx context.Context, key, fingerprint string, lock time.Duration) (Record, bool, error)
+
+	// Complete stores the response to the request with the supplied key for
+	// the supplied TTL, if the request still holds the key as its owner.
+	Complete(ctx context.Context, key, owner string, res Response, ttl time.Duration) error
+
+	// Abort forgets the request with the supplied key, so that it may be
+	// retried, if the request still holds the key as its owner.
+	Abort(ctx context.Context, key, owner string) error
+}
@@ -0,0 +1,115 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const errOwnerToken = "failed to generate owner token"
+
+// MemoryStore holds records in memory. Requests are only replayed by the
+// replica that served them.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*entry
+	now     func() time.Time
+	sweep   time.Time
+}
+
+type entry struct {
+	rec     Record
+	expires time.Time
+}
+
+// MemoryStoreOpt modifies a memory store.
+type MemoryStoreOpt func(s *MemoryStore)
+
+// WithClock sets the clock used by a memory store.
+func WithClock(now func() time.Time) MemoryStoreOpt {
+	return func(s *MemoryStore) {
+		s.now = now
+	}
+}
+
+// NewMemoryStore constructs an in-memory store.
+func NewMemoryStore(opts ...MemoryStoreOpt) *MemoryStore {
+	s := &MemoryStore{
+		records: map[string]*entry{},
+		now:     time.Now,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.sweep = s.now()
+	return s
+}
+
+// Begin records that a request is in flight unless the key is recorded.
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string, lock time.Duration) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.evict(now)
+
+	if e, ok := s.records[key]; ok && now.Before(e.expires) {
+		rec := e.rec
+		rec.Owner = ""
+		return rec, false, nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return Record{}, false, errors.Wrap(err, errOwnerToken)
+	}
+	rec := Record{Fingerprint: fingerprint, Owner: hex.EncodeToString(b)}
+	s.records[key] = &entry{rec: rec, expires: now.Add(lock)}
+	return rec, true, nil
+}
+
+// Complete stores the response to a request. Responses to requests whose
+// lock expired before they completed are not stored, even if the key has not
+// since been taken over by another request.
+func (s *MemoryStore) Complete(_ context.Context, key, owner string, res Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	e, ok := s.records[key]
+	if !ok || !s.holds(e, owner, now) {
+		return nil
+	}
+	e.rec.Response = &res
+	e.expires = now.Add(ttl)
+	return nil
+}
+
+// Abort forgets a request, unless its lock expired.
+func (s *MemoryStore) Abort(_ context.Context, key, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.records[key]; ok && s.holds(e, owner, s.now()) {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+// holds reports whether the owner holds the in flight record.
+func (s *MemoryStore) holds(e *entry, owner string, now time.Time) bool {
+	return e.rec.Owner == owner && e.rec.Response == nil && now.Before(e.expires)
+}
+
+// evict removes expired records. It runs at most once a minute.
+func (s *MemoryStore) evict(now time.Time) {
+	if now.Sub(s.sweep) < time.Minute {
+		return
+	}
+	s.sweep = now
+	for k, e := range s.records {
+		if !now.Before(e.expires) {
+			delete(s.records, k)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestMemoryStoreBegin(t *testing.T) {
+	res := Response{Status: http.StatusCreated, Header: http.Header{}, Body: []byte("{}")}
+	type arguments struct {
+		complete bool
+		abort    bool
+		elapsed  time.Duration
+	}
+	type want struct {
+		rec     Record
+		started bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"InFlight": {
+			reason: "A key should be held while its request is in flight.",
+			want:   want{rec: Record{Fingerprint: "a"}},
+		},
+		"LockExpired": {
+			reason: "A key should be released once its lock expires.",
+			args:   arguments{elapsed: 2 * time.Minute},
+			want:   want{rec: Record{Fingerprint: "b"}, started: true},
+		},
+		"Completed": {
+			reason: "The response to a completed request should be returned.",
+			args:   arguments{complete: true, elapsed: 2 * time.Minute},
+			want:   want{rec: Record{Fingerprint: "a", Response: &res}},
+		},
+		"Expired": {
+			reason: "A key should be released once its response expires.",
+			args:   arguments{complete: true, elapsed: 2 * time.Hour},
+			want:   want{rec: Record{Fingerprint: "b"}, started: true},
+		},
+		"Aborted": {
+			reason: "A key should be released when its request is aborted.",
+			args:   arguments{abort: true},
+			want:   want{rec: Record{Fingerprint: "b"}, started: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			s := NewMemoryStore(WithClock(func() time.Time { return now }))
+			ctx := context.Background()
+			first, _, err := s.Begin(ctx, "k", "a", time.Minute)
+			if err != nil {
+				t.Fatalf("Begin(...): %v", err)
+			}
+			if tc.args.complete {
+				_ = s.Complete(ctx, "k", first.Owner, res, time.Hour)
+			}
+			if tc.args.abort {
+				_ = s.Abort(ctx, "k", first.Owner)
+			}
+			now = now.Add(tc.args.elapsed)
+			rec, started, err := s.Begin(ctx, "k", "b", time.Minute)
+			if err != nil {
+				t.Fatalf("Begin(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, want{rec: rec, started: started}, cmp.AllowUnexported(want{}), cmpopts.IgnoreFields(Record{}, "Owner")); diff != "" {
+				t.Errorf("\n%s\nBegin(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTakeover(t *testing.T) {
+	first := Response{Status: http.StatusCreated, Body: []byte("first")}
+	second := Response{Status: http.StatusCreated, Body: []byte("second")}
+	cases := map[string]struct {
+		reason   string
+		complete bool
+		want     *Response
+	}{
+		"Abort": {
+			reason: "A request whose lock expired should not abort the request that took over its key.",
+			want:   nil,
+		},
+		"Complete": {
+			reason:   "A request whose lock expired should not store its response in place of the request that took over its key.",
+			complete: true,
+			want:     &second,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			s := NewMemoryStore(WithClock(func() time.Time { return now }))
+			ctx := context.Background()
+			a, _, _ := s.Begin(ctx, "k", "a", time.Minute)
+			now = now.Add(2 * time.Minute)
+			b, started, _ := s.Begin(ctx, "k", "a", time.Minute)
+			if !started {
+				t.Fatalf("\n%s\nBegin(...): key was not released when its lock expired", tc.reason)
+			}
+
+			// The first request finishes after its key was taken over.
+			_ = s.Complete(ctx, "k", a.Owner, first, time.Hour)
+			_ = s.Abort(ctx, "k", a.Owner)
+			if tc.complete {
+				_ = s.Complete(ctx, "k", b.Owner, second, time.Hour)
+			}
+			got, started, _ := s.Begin(ctx, "k", "a", time.Minute)
+			if started {
+				t.Errorf("\n%s\nBegin(...): key was released", tc.reason)
+			}
+			if diff := cmp.Diff(tc.want, got.Response); diff != "" {
+				t.Errorf("\n%s\nBegin(...): -want response, +got response:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
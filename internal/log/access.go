@@ -0,0 +1,194 @@
+package log
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/upbound/build-submodule-demo/internal"
+	"github.com/upbound/build-submodule-demo/internal/generics"
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+)
+
+// Code in this package matches work from @negz in
+// https://github.com/upbound/xgql
+
+// Access log levels and formats.
+const (
+	LevelInfo  = "info"
+	LevelDebug = "debug"
+	LevelOff   = "off"
+
+	FormatJSON     = "json"
+	FormatCombined = "combined"
+)
+
+const (
+	redacted      = "REDACTED"
+	combinedTime  = "02/Jan/2006:15:04:05 -0700"
+	msgAccess     = "Handled request"
+	msgAccessLine = "%s - %s [%s] %q %d %d %q %q route=%q trace_id=%q duration=%s"
+)
+
+// sensitiveHeaders are never written to the access log in clear text.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// AccessFormatter provides an access log formatter that logs requests at a
+// configurable level, samples successful requests and redacts credentials.
+type AccessFormatter struct {
+	log     logging.Logger
+	opts    internal.AccessLogOptions
+	headers []string
+	sample  func() float64
+}
+
+// NewAccessFormatter constructs a new access log formatter.
+func NewAccessFormatter(l logging.Logger, opts internal.AccessLogOptions) *AccessFormatter {
+	return &AccessFormatter{
+		log:     l,
+		opts:    opts,
+		headers: generics.Reduce(opts.AccessLogHeaders, http.CanonicalHeaderKey),
+		sample:  rand.Float64, //nolint:gosec // sampling does not need a secure source.
+	}
+}
+
+// NewLogEntry creates a new access log entry.
+func (f *AccessFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
+	return &accessEntry{f: f, req: r, start: time.Now()}
+}
+
+// SetPrincipal records the authenticated principal for the request in its
+// access log entry, if any.
+func SetPrincipal(r *http.Request, principal string) {
+	if e, ok := middleware.GetLogEntry(r).(*accessEntry); ok {
+		e.principal = principal
+	}
+}
+
+type accessEntry struct {
+	f         *AccessFormatter
+	req       *http.Request
+	start     time.Time
+	principal string
+}
+
+// Write writes an access log entry if the request is not excluded or sampled
+// out.
+func (e *accessEntry) Write(status, bytes int, _ http.Header, elapsed time.Duration, _ any) {
+	if !e.f.shouldLog(e.req, status, elapsed) {
+		return
+	}
+	log := e.f.log.Info
+	if e.f.opts.AccessLogLevel == LevelDebug {
+		log = e.f.log.Debug
+	}
+	route := ""
+	if rctx := chi.RouteContext(e.req.Context()); rctx != nil {
+		route = rctx.RoutePattern()
+	}
+	traceID := ""
+	if sc := trace.SpanContextFromContext(e.req.Context()); sc.HasTraceID() {
+		traceID = sc.TraceID().String()
+	}
+	uri := RedactURI(e.req.RequestURI, e.f.opts.AccessLogRedactParams)
+	fwd := forwarded.FromRequest(e.req)
+
+	if e.f.opts.AccessLogFormat == FormatCombined {
+		log(fmt.Sprintf(msgAccessLine,
+			orDash(fwd.ClientIP),
+			orDash(e.principal),
+			e.start.Format(combinedTime),
+			fmt.Sprintf("%s %s %s", e.req.Method, uri, e.req.Proto),
+			status,
+			bytes,
+			orDash(e.req.Referer()),
+			orDash(e.req.UserAgent()),
+			route,
+			traceID,
+			elapsed,
+		))
+		return
+	}
+
+	kv := []any{
+		"id", middleware.GetReqID(e.req.Context()),
+		"method", e.req.Method,
+		"tls", e.req.TLS != nil,
+		"scheme", fwd.Scheme,
+		"host", fwd.Host,
+		"uri", uri,
+		"route", route,
+		"protocol", e.req.Proto,
+		"remote", e.req.RemoteAddr,
+		"client", fwd.ClientIP,
+		"principal", e.principal,
+		"traceID", traceID,
+		"status", status,
+		"bytes", bytes,
+		"duration", elapsed,
+	}
+	for _, h := range e.f.headers {
+		if v := e.req.Header.Get(h); v != "" {
+			if generics.Contains(sensitiveHeaders, h) {
+				v = redacted
+			}
+			kv = append(kv, h, v)
+		}
+	}
+	log(msgAccess, kv...)
+}
+
+// Panic logs a panic.
+func (e *accessEntry) Panic(v any, stack []byte) {
+	e.f.log.Debug("Paniced while handling request", "stack", stack, "panic", v)
+}
+
+// shouldLog determines whether a request is logged. Errors and slow requests
+// are always logged unless the path is excluded; successful requests are
+// sampled.
+func (f *AccessFormatter) shouldLog(r *http.Request, status int, elapsed time.Duration) bool {
+	if f.opts.AccessLogLevel == LevelOff || generics.Contains(f.opts.AccessLogExcludePaths, r.URL.Path) {
+		return false
+	}
+	if status >= http.StatusBadRequest || elapsed >= f.opts.AccessLogSlowThreshold {
+		return true
+	}
+	return f.sample() < f.opts.AccessLogSampleRate
+}
+
+// RedactURI replaces the values of the supplied query parameters in a request
+// URI.
+func RedactURI(uri string, params []string) string {
+	u, err := url.ParseRequestURI(uri)
+	if err != nil || u.RawQuery == "" {
+		return uri
+	}
+	q := u.Query()
+	changed := false
+	for _, p := range params {
+		if _, ok := q[p]; ok {
+			q.Set(p, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return uri
+	}
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
+
+func orDash(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,155 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/build-submodule-demo/internal"
+)
+
+func TestRedactURI(t *testing.T) {
+	type arguments struct {
+		uri    string
+		params []string
+	}
+	type want struct {
+		uri string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"NoQuery": {
+			reason: "A URI without a query should be returned unchanged.",
+			args: arguments{
+				uri:    "/v1/demo",
+				params: []string{"token"},
+			},
+			want: want{
+				uri: "/v1/demo",
+			},
+		},
+		"NoSensitiveParams": {
+			reason: "A URI without sensitive parameters should be returned unchanged.",
+			args: arguments{
+				uri:    "/v1/demo?page=2&size=10",
+				params: []string{"token"},
+			},
+			want: want{
+				uri: "/v1/demo?page=2&size=10",
+			},
+		},
+		"SensitiveParams": {
+			reason: "Values of sensitive parameters should be redacted.",
+			args: arguments{
+				uri:    "/v1/demo?page=2&token=secret",
+				params: []string{"token", "access_token"},
+			},
+			want: want{
+				uri: "/v1/demo?page=2&token=REDACTED",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := RedactURI(tc.args.uri, tc.args.params)
+			if diff := cmp.Diff(tc.want.uri, got); diff != "" {
+				t.Errorf("\n%s\nRedactURI(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestShouldLog(t *testing.T) {
+	opts := internal.AccessLogOptions{
+		AccessLogLevel:         LevelInfo,
+		AccessLogSampleRate:    0.5,
+		AccessLogSlowThreshold: time.Second,
+		AccessLogExcludePaths:  []string{"/livez"},
+	}
+	type arguments struct {
+		path    string
+		status  int
+		elapsed time.Duration
+		sample  float64
+	}
+	type want struct {
+		log bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Excluded": {
+			reason: "Requests to excluded paths should never be logged.",
+			args: arguments{
+				path:   "/livez",
+				status: http.StatusInternalServerError,
+			},
+			want: want{
+				log: false,
+			},
+		},
+		"Error": {
+			reason: "Failed requests should always be logged.",
+			args: arguments{
+				path:   "/v1/demo",
+				status: http.StatusUnauthorized,
+				sample: 0.9,
+			},
+			want: want{
+				log: true,
+			},
+		},
+		"Slow": {
+			reason: "Slow requests should always be logged.",
+			args: arguments{
+				path:    "/v1/demo",
+				status:  http.StatusOK,
+				elapsed: 2 * time.Second,
+				sample:  0.9,
+			},
+			want: want{
+				log: true,
+			},
+		},
+		"SampledIn": {
+			reason: "Successful requests within the sample rate should be logged.",
+			args: arguments{
+				path:   "/v1/demo",
+				status: http.StatusOK,
+				sample: 0.1,
+			},
+			want: want{
+				log: true,
+			},
+		},
+		"SampledOut": {
+			reason: "Successful requests outside the sample rate should not be logged.",
+			args: arguments{
+				path:   "/v1/demo",
+				status: http.StatusOK,
+				sample: 0.9,
+			},
+			want: want{
+				log: false,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := NewAccessFormatter(nil, opts)
+			f.sample = func() float64 { return tc.args.sample }
+			got := f.shouldLog(httptest.NewRequest(http.MethodGet, tc.args.path, nil), tc.args.status, tc.args.elapsed)
+			if diff := cmp.Diff(tc.want.log, got); diff != "" {
+				t.Errorf("\n%s\nshouldLog(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
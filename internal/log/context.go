@@ -0,0 +1,22 @@
+package log
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+)
+
+type logctxkey int
+
+const loggerKey logctxkey = 0
+
+// NewContext returns a copy of the supplied context that carries the logger.
+func NewContext(ctx context.Context, l logging.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext extracts the logger from the supplied context.
+func FromContext(ctx context.Context) (logging.Logger, bool) {
+	l, ok := ctx.Value(loggerKey).(logging.Logger)
+	return l, ok
+}
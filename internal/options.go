@@ -2,6 +2,7 @@ package internal
 
 import (
 	"net/url"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 )
@@ -11,10 +12,36 @@ type CommonOptions struct {
 	Log          logging.Logger `kong:"-"`
 	Debug        bool           `name:"debug" env:"DEBUG" short:"d" default:"false" help:"Run with debug logging."`
 	DevMode      bool           `name:"dev-mode" env:"DEV_MODE" default:"false" help:"Enables logging dev mode."`
-	EnableGZip   bool           `name:"enable-gzip" env:"ENABLE_GZIP" default:"true" help:"Enable gzip compression. Default value = true"`
+	EnableGZip   bool           `name:"enable-gzip" env:"ENABLE_GZIP" default:"true" help:"Enable response compression. Default value = true"`
 	PrivatePort  int            `default:"8089" help:"Port for private API server."`
 	IsEnterprise bool           `kong:"-"`
+
+	RequestIDHeader    string `name:"request-id-header" env:"REQUEST_ID_HEADER" default:"X-Request-ID" help:"Trusted incoming header to take request IDs from. IDs are always generated if empty."`
+	ResponseValidation string `name:"response-validation" env:"RESPONSE_VALIDATION" default:"auto" enum:"auto,off,log,fail" help:"Validate responses against the OpenAPI spec: auto (log in dev mode), off, log or fail."`
+
+	TrustedProxies []string `name:"trusted-proxies" env:"TRUSTED_PROXIES" help:"CIDRs of proxies whose Forwarded and X-Forwarded-* headers are trusted."`
+
+	PrivateTimeouts TimeoutOptions `embed:"" prefix:"private-" envprefix:"PRIVATE_"`
+	MetricsTimeouts TimeoutOptions `embed:"" prefix:"metrics-" envprefix:"METRICS_"`
+
 	MetricsOptions
+	OpenAPIOptions
+	CompressionOptions
+	AccessLogOptions
+	ErrorReportingOptions
+}
+
+// TimeoutOptions options related to the timeouts of a server and the limits
+// of its requests. The request timeout and maximum body size may be
+// overridden per route with the x-timeout and x-max-body-bytes OpenAPI
+// extensions.
+type TimeoutOptions struct {
+	ReadTimeout       time.Duration `name:"read-timeout" env:"READ_TIMEOUT" default:"5s" help:"Maximum duration for reading a request, including its body."`
+	ReadHeaderTimeout time.Duration `name:"read-header-timeout" env:"READ_HEADER_TIMEOUT" default:"5s" help:"Maximum duration for reading request headers."`
+	WriteTimeout      time.Duration `name:"write-timeout" env:"WRITE_TIMEOUT" default:"10s" help:"Maximum duration for writing a response."`
+	IdleTimeout       time.Duration `name:"idle-timeout" env:"IDLE_TIMEOUT" default:"120s" help:"Maximum duration a keep-alive connection is idle."`
+	RequestTimeout    time.Duration `name:"request-timeout" env:"REQUEST_TIMEOUT" default:"9s" help:"Deadline of request contexts, leaving time to respond within the write timeout. 0 is unlimited."`
+	MaxBodyBytes      int64         `name:"max-body-bytes" env:"MAX_BODY_BYTES" default:"1048576" help:"Maximum size in bytes of request bodies. 0 is unlimited."`
 }
 
 // ProductMetricsOptions are common options for consumers of the accounts build-submodule-demo.
@@ -22,8 +49,49 @@ type ProductMetricsOptions struct {
 	Host url.URL `name:"product-metrics-host" default:"http://product-metrics-private:8080" help:"Product Metrics build-submodule-demo host."`
 }
 
-// MetricsOptions options related to prometheus metrics server
+// MetricsOptions options related to metrics exporters.
 type MetricsOptions struct {
-	MetricsPort int  `default:"8085" help:"Port for metrics server."`
-	Metrics     bool `name:"metrics" default:"true" negatable:"" help:"Enable Prometheus metrics exporter."`
+	MetricsPort  int               `default:"8085" help:"Port for metrics server."`
+	Metrics      bool              `name:"metrics" default:"true" negatable:"" help:"Enable metrics exporters."`
+	MetricsMode  string            `name:"metrics-mode" env:"METRICS_MODE" default:"prometheus" enum:"prometheus,otlp,both" help:"Metrics export mode: prometheus (pull), otlp (push) or both."`
+	OTLPEndpoint string            `name:"otlp-endpoint" env:"OTLP_ENDPOINT" default:"localhost:4318" help:"OTLP HTTP collector endpoint (host:port) for pushing metrics."`
+	OTLPInsecure bool              `name:"otlp-insecure" env:"OTLP_INSECURE" default:"false" help:"Push metrics to the OTLP endpoint without TLS."`
+	OTLPInterval time.Duration     `name:"otlp-interval" env:"OTLP_INTERVAL" default:"60s" help:"Interval between OTLP metric pushes."`
+	OTLPHeaders  map[string]string `name:"otlp-headers" env:"OTLP_HEADERS" help:"Headers sent with OTLP metric pushes, e.g. key1=value1;key2=value2."`
+
+	MetricsSemconv string `name:"metrics-semconv" env:"METRICS_SEMCONV" default:"stable" enum:"stable,dup,legacy" help:"HTTP metrics semantic conventions: stable, dup (stable and legacy) or legacy."`
+}
+
+// OpenAPIOptions options related to serving the OpenAPI specs of servers.
+type OpenAPIOptions struct {
+	OpenAPISpec       bool   `name:"openapi-spec" env:"OPENAPI_SPEC" default:"true" negatable:"" help:"Serve the OpenAPI spec of each server at /openapi.json and /openapi.yaml."`
+	OpenAPIDocs       bool   `name:"openapi-docs" env:"OPENAPI_DOCS" default:"false" negatable:"" help:"Serve interactive API documentation at /docs."`
+	OpenAPIDocsScript string `name:"openapi-docs-script" env:"OPENAPI_DOCS_SCRIPT" default:"https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js" help:"URL of the Redoc script loaded by the documentation page."`
+}
+
+// CompressionOptions options related to response compression, which is
+// enabled by EnableGZip.
+type CompressionOptions struct {
+	CompressionEncodings    []string `name:"compression-encodings" env:"COMPRESSION_ENCODINGS" default:"zstd,br,gzip" help:"Supported response encodings in order of preference."`
+	CompressionMinSize      int      `name:"compression-min-size" env:"COMPRESSION_MIN_SIZE" default:"1024" help:"Minimum size in bytes of compressed responses."`
+	CompressionContentTypes []string `name:"compression-content-types" env:"COMPRESSION_CONTENT_TYPES" default:"text/*,application/json,application/*+json,application/javascript,application/xml,application/yaml,image/svg+xml" help:"Media types of compressed responses."`
+}
+
+// AccessLogOptions options related to request access logging.
+type AccessLogOptions struct {
+	AccessLogLevel         string        `name:"access-log-level" env:"ACCESS_LOG_LEVEL" default:"info" enum:"info,debug,off" help:"Level at which requests are logged."`
+	AccessLogFormat        string        `name:"access-log-format" env:"ACCESS_LOG_FORMAT" default:"json" enum:"json,combined" help:"Access log format: json (structured) or combined (Apache combined log format)."`
+	AccessLogSampleRate    float64       `name:"access-log-sample-rate" env:"ACCESS_LOG_SAMPLE_RATE" default:"1" help:"Fraction of successful requests to log. Errors and slow requests are always logged."`
+	AccessLogSlowThreshold time.Duration `name:"access-log-slow-threshold" env:"ACCESS_LOG_SLOW_THRESHOLD" default:"1s" help:"Requests taking longer than this are always logged."`
+	AccessLogExcludePaths  []string      `name:"access-log-exclude-paths" env:"ACCESS_LOG_EXCLUDE_PATHS" default:"/livez,/readyz,/metrics" help:"Request paths that are not logged."`
+	AccessLogHeaders       []string      `name:"access-log-headers" env:"ACCESS_LOG_HEADERS" default:"Referer,User-Agent" help:"Request headers included in the access log."`
+	AccessLogRedactParams  []string      `name:"access-log-redact-params" env:"ACCESS_LOG_REDACT_PARAMS" default:"token,access_token,id_token,refresh_token" help:"Query parameters whose values are redacted."`
+}
+
+// ErrorReportingOptions options related to forwarding unexpected errors, such
+// as recovered panics, to an error reporting service.
+type ErrorReportingOptions struct {
+	ErrorReporter   string  `name:"error-reporter" env:"ERROR_REPORTER" default:"none" enum:"none,file,http" help:"Error reporting sink: none, file (JSON lines) or http (JSON POST)."`
+	ErrorReportFile string  `name:"error-report-file" env:"ERROR_REPORT_FILE" default:"errors.jsonl" help:"File that errors are appended to when using the file reporter."`
+	ErrorReportURL  url.URL `name:"error-report-url" env:"ERROR_REPORT_URL" help:"Endpoint that errors are posted to when using the http reporter."`
 }
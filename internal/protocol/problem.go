@@ -0,0 +1,34 @@
+package protocol
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// A Problem describes an error as RFC 7807 problem details.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"requestID,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// A FieldError describes why a single field of a request is invalid.
+type FieldError struct {
+	// In is the location of the field, e.g. path, query, header or body.
+	In string `json:"in"`
+	// Field is the parameter name or, for bodies, the JSON pointer to the
+	// field.
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Error returns the title and detail of the problem, allowing it to be
+// returned as an error by handlers.
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
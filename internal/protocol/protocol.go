@@ -0,0 +1,19 @@
+// Package protocol defines the headers, cookies and error bodies that are part
+// of the API protocol, so that servers and clients can refer to them without
+// depending on each other.
+package protocol
+
+const (
+	// CSRFCookieName is the name of the cookie holding the double-submit
+	// CSRF token. It is readable by scripts so that it can be echoed back.
+	CSRFCookieName = "csrf_token"
+
+	// CSRFHeaderName is the request header the CSRF token is echoed in.
+	CSRFHeaderName = "X-CSRF-Token"
+
+	// HeaderIdempotencyKey is the request header holding an idempotency key.
+	HeaderIdempotencyKey = "Idempotency-Key"
+
+	// HeaderIdempotentReplayed is set on replayed responses.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
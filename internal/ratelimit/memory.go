@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryStore holds token buckets in memory. Limits are enforced per replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	sweep   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// MemoryStoreOpt modifies a memory store.
+type MemoryStoreOpt func(s *MemoryStore)
+
+// WithClock sets the clock used by a memory store.
+func WithClock(now func() time.Time) MemoryStoreOpt {
+	return func(s *MemoryStore) {
+		s.now = now
+	}
+}
+
+// NewMemoryStore constructs an in-memory store.
+func NewMemoryStore(opts ...MemoryStoreOpt) *MemoryStore {
+	s := &MemoryStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.sweep = s.now()
+	return s
+}
+
+// Take takes a token from the bucket for the supplied key.
+func (s *MemoryStore) Take(_ context.Context, key string, l Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.evict(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+	b.limit = l
+
+	res := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = refill(1-b.tokens, l.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = refill(float64(l.Burst)-b.tokens, l.Rate)
+	return res, nil
+}
+
+// evict removes buckets that have been idle for at least a minute and would
+// have refilled since. It runs at most once a minute.
+func (s *MemoryStore) evict(now time.Time) {
+	if now.Sub(s.sweep) < time.Minute {
+		return
+	}
+	s.sweep = now
+	for k, b := range s.buckets {
+		idle := now.Sub(b.last)
+		if idle >= time.Minute && idle >= refill(float64(b.limit.Burst)-b.tokens, b.limit.Rate) {
+			delete(s.buckets, k)
+		}
+	}
+}
+
+// refill returns the time taken to add the supplied number of tokens.
+func refill(tokens, rate float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	type arguments struct {
+		limit   Limit
+		takes   int
+		elapsed time.Duration
+	}
+	type want struct {
+		res Result
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Allowed": {
+			reason: "A token should be taken from a new bucket.",
+			args: arguments{
+				limit: Limit{Rate: 1, Burst: 2},
+			},
+			want: want{
+				res: Result{Allowed: true, Remaining: 1, Reset: time.Second},
+			},
+		},
+		"Exhausted": {
+			reason: "A request should be rejected once the burst is used up.",
+			args: arguments{
+				limit: Limit{Rate: 1, Burst: 2},
+				takes: 2,
+			},
+			want: want{
+				res: Result{Allowed: false, Remaining: 0, Reset: 2 * time.Second, RetryAfter: time.Second},
+			},
+		},
+		"Refilled": {
+			reason: "Tokens should be added at the rate of the limit.",
+			args: arguments{
+				limit:   Limit{Rate: 2, Burst: 2},
+				takes:   2,
+				elapsed: time.Second,
+			},
+			want: want{
+				res: Result{Allowed: true, Remaining: 1, Reset: 500 * time.Millisecond},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			s := NewMemoryStore(WithClock(func() time.Time { return now }))
+			for i := 0; i < tc.args.takes; i++ {
+				_, _ = s.Take(context.Background(), "key", tc.args.limit)
+			}
+			now = now.Add(tc.args.elapsed)
+			res, err := s.Take(context.Background(), "key", tc.args.limit)
+			if err != nil {
+				t.Fatalf("\n%s\nTake(...): unexpected error: %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want.res, res); diff != "" {
+				t.Errorf("\n%s\nTake(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
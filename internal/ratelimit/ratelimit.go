@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errParseLimit = "failed to parse limit, expected <rate>/<burst>"
+)
+
+// A Limit is a token bucket limit. Tokens are added at Rate per second up to
+// a maximum of Burst.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit parses a limit in the form <rate>/<burst>, e.g. 10/20.
+func ParseLimit(s string) (Limit, error) {
+	rate, burst, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, errors.New(errParseLimit)
+	}
+	r, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+	if err != nil {
+		return Limit{}, errors.Wrap(err, errParseLimit)
+	}
+	b, err := strconv.Atoi(strings.TrimSpace(burst))
+	if err != nil {
+		return Limit{}, errors.Wrap(err, errParseLimit)
+	}
+	return Limit{Rate: r, Burst: b}, nil
+}
+
+// A Result is the outcome of taking a token from a bucket.
+type Result struct {
+	// Allowed is true if a token was taken.
+	Allowed bool
+
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+
+	// RetryAfter is the time until a token is available if none was taken.
+	RetryAfter time.Duration
+}
+
+// A Store holds token buckets. Implementations backed by a shared store, such
+// as Redis, allow limits to be enforced across replicas and must take tokens
+// atomically.
+type Store interface {
+	Take(ctx context.Context, key string, l Limit) (Result, error)
+}
@@ -0,0 +1,45 @@
+package reporting
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errOpenFile    = "failed to open report file"
+	errMarshal     = "failed to marshal event"
+	errWriteReport = "failed to write event"
+)
+
+// FileReporter appends events as JSON lines to a local file. It is intended as
+// a stand-in for an error reporting service in development and tests.
+type FileReporter struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileReporter constructs a reporter that writes to the supplied path.
+func NewFileReporter(path string) *FileReporter {
+	return &FileReporter{path: path}
+}
+
+// Report appends the event to the file.
+func (r *FileReporter) Report(_ context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, errMarshal)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return errors.Wrap(err, errOpenFile)
+	}
+	defer f.Close() //nolint:errcheck
+	_, err = f.Write(append(b, '\n'))
+	return errors.Wrap(err, errWriteReport)
+}
@@ -0,0 +1,49 @@
+package reporting
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ Reporter = &FileReporter{}
+var _ Reporter = &HTTPReporter{}
+var _ Reporter = &MockReporter{}
+var _ Reporter = NopReporter{}
+
+func TestFileReporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	events := []Event{
+		{Time: time.Unix(1, 0).UTC(), RequestID: "a", Message: "boom"},
+		{Time: time.Unix(2, 0).UTC(), RequestID: "b", Message: "bang", Stack: "stack"},
+	}
+	r := NewFileReporter(path)
+	for _, e := range events {
+		if err := r.Report(context.Background(), e); err != nil {
+			t.Fatalf("Report(...): unexpected error: %v", err)
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open(...): unexpected error: %v", err)
+	}
+	defer f.Close()
+	got := []Event{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		e := Event{}
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatalf("Unmarshal(...): unexpected error: %v", err)
+		}
+		got = append(got, e)
+	}
+	if diff := cmp.Diff(events, got); diff != "" {
+		t.Errorf("\nReport(...): -want events, +got events:\n%s", diff)
+	}
+}
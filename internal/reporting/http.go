@@ -0,0 +1,71 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	shttp "github.com/upbound/build-submodule-demo/internal/client/http"
+)
+
+const (
+	errCreateRequest = "could not create report request"
+	errDoRequest     = "report request failed"
+	errResponse      = "report request was not successful"
+)
+
+// HTTPReporter posts events as JSON to an HTTP endpoint, such as a webhook or
+// a local collector standing in for an error reporting service.
+type HTTPReporter struct {
+	endpoint url.URL
+	client   shttp.Client
+}
+
+// HTTPReporterOpt modifies an HTTP reporter.
+type HTTPReporterOpt func(r *HTTPReporter)
+
+// WithClient sets the HTTP client for the reporter.
+func WithClient(c shttp.Client) HTTPReporterOpt {
+	return func(r *HTTPReporter) {
+		r.client = c
+	}
+}
+
+// NewHTTPReporter constructs a reporter that posts to the supplied endpoint.
+func NewHTTPReporter(endpoint url.URL, opts ...HTTPReporterOpt) *HTTPReporter {
+	r := &HTTPReporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Report posts the event to the endpoint.
+func (r *HTTPReporter) Report(ctx context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, errMarshal)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint.String(), bytes.NewReader(b))
+	if err != nil {
+		return errors.Wrap(err, errCreateRequest)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := r.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, errDoRequest)
+	}
+	defer res.Body.Close() //nolint:errcheck
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.Errorf("%s: status %d", errResponse, res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package reporting
+
+import (
+	"context"
+)
+
+// MockReporter is a mock reporter.
+type MockReporter struct {
+	ReportFn func(ctx context.Context, e Event) error
+}
+
+// Report calls the underlying ReportFn.
+func (m *MockReporter) Report(ctx context.Context, e Event) error {
+	return m.ReportFn(ctx, e)
+}
@@ -0,0 +1,31 @@
+package reporting
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal"
+)
+
+// Reporters.
+const (
+	ReporterNone = "none"
+	ReporterFile = "file"
+	ReporterHTTP = "http"
+)
+
+const errNoReportURL = "an error report URL is required by the http reporter"
+
+// New constructs the reporter selected by the supplied options.
+func New(opts internal.ErrorReportingOptions) (Reporter, error) {
+	switch opts.ErrorReporter {
+	case ReporterFile:
+		return NewFileReporter(opts.ErrorReportFile), nil
+	case ReporterHTTP:
+		if opts.ErrorReportURL.Host == "" {
+			return nil, errors.New(errNoReportURL)
+		}
+		return NewHTTPReporter(opts.ErrorReportURL), nil
+	default:
+		return NopReporter{}, nil
+	}
+}
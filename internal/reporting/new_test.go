@@ -0,0 +1,54 @@
+package reporting
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal"
+)
+
+func TestNew(t *testing.T) {
+	type want struct {
+		r   Reporter
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		opts   internal.ErrorReportingOptions
+		want   want
+	}{
+		"None": {
+			reason: "No reporter should be constructed by default.",
+			opts:   internal.ErrorReportingOptions{ErrorReporter: ReporterNone},
+			want:   want{r: NopReporter{}},
+		},
+		"HTTP": {
+			reason: "An HTTP reporter should post to the configured URL.",
+			opts: internal.ErrorReportingOptions{
+				ErrorReporter:  ReporterHTTP,
+				ErrorReportURL: url.URL{Scheme: "http", Host: "collector:8080", Path: "/errors"},
+			},
+			want: want{r: NewHTTPReporter(url.URL{Scheme: "http", Host: "collector:8080", Path: "/errors"})},
+		},
+		"HTTPWithoutURL": {
+			reason: "An HTTP reporter should not be constructed without a URL to post to.",
+			opts:   internal.ErrorReportingOptions{ErrorReporter: ReporterHTTP},
+			want:   want{err: errors.New(errNoReportURL)},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r, err := New(tc.opts)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nNew(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.r, r, cmp.Comparer(func(a, b *HTTPReporter) bool { return a.endpoint == b.endpoint })); diff != "" {
+				t.Errorf("\n%s\nNew(...): -want reporter, +got reporter:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package reporting
+
+import (
+	"context"
+	"time"
+)
+
+// An Event describes an unexpected failure, such as a recovered panic, that
+// should be forwarded to an error reporting service.
+type Event struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"requestID,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	URI       string    `json:"uri,omitempty"`
+	Message   string    `json:"message"`
+	Stack     string    `json:"stack,omitempty"`
+}
+
+// A Reporter reports events to an error reporting service.
+type Reporter interface {
+	Report(ctx context.Context, e Event) error
+}
+
+// NopReporter discards all events.
+type NopReporter struct{}
+
+// Report does nothing.
+func (NopReporter) Report(_ context.Context, _ Event) error {
+	return nil
+}
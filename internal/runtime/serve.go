@@ -3,10 +3,12 @@ package runtime
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"golang.org/x/net/netutil"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -16,8 +18,9 @@ const (
 )
 
 type srv struct {
-	name string
-	log  logging.Logger
+	name     string
+	log      logging.Logger
+	maxConns int
 }
 
 // SrvOpt modifies a server.
@@ -37,6 +40,14 @@ func WithLogger(l logging.Logger) SrvOpt {
 	}
 }
 
+// WithMaxConnections limits the number of simultaneous connections accepted
+// by the server listener. Zero or less is unlimited.
+func WithMaxConnections(n int) SrvOpt {
+	return func(s *srv) {
+		s.maxConns = n
+	}
+}
+
 // StartServer - run an http server with shutdown monitoring
 func StartServer(h *http.Server, g *errgroup.Group, sd <-chan struct{}, opts ...SrvOpt) {
 	s := &srv{
@@ -49,7 +60,7 @@ func StartServer(h *http.Server, g *errgroup.Group, sd <-chan struct{}, opts ...
 	log := s.log.WithValues("Server", s.name, "Address", h.Addr)
 	g.Go(func() error {
 		log.Debug("Starting server.")
-		if err := h.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		if err := serve(h, s.maxConns); !errors.Is(err, http.ErrServerClosed) {
 			log.Info(errFailedServing, "Error", err)
 			return err
 		}
@@ -69,7 +80,25 @@ func StartServer(h *http.Server, g *errgroup.Group, sd <-chan struct{}, opts ...
 	})
 }
 
-// StartUpDown - run a generic blocking function in thread with shutdown monitoring
+// serve accepts connections for the server, limiting the number of open
+// connections if maxConns is positive.
+func serve(h *http.Server, maxConns int) error {
+	if maxConns <= 0 {
+		return h.ListenAndServe()
+	}
+	addr := h.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return h.Serve(netutil.LimitListener(l, maxConns))
+}
+
+// StartUpDown - run a generic blocking function in thread with shutdown
+// monitoring. A nil up function only registers down as a shutdown hook.
 func StartUpDown(up func() error, down func(context.Context) error, g *errgroup.Group, sd <-chan struct{}, opts ...SrvOpt) {
 	s := &srv{
 		name: "default",
@@ -80,6 +109,9 @@ func StartUpDown(up func() error, down func(context.Context) error, g *errgroup.
 	}
 	log := s.log.WithValues("Server", s.name)
 	g.Go(func() error {
+		if up == nil {
+			return nil
+		}
 		log.Debug("Starting server.")
 		if err := up(); err != nil {
 			log.Info(errFailedServing, "Error", err)
@@ -1,15 +1,18 @@
 package entities
 
 import (
-	"net/http"
+	"context"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+
+	api "github.com/upbound/build-submodule-demo/internal/api/demo"
+	"github.com/upbound/build-submodule-demo/internal/server/strict"
 )
 
 // GetV1Demo - [/v1/demo] - Demo
-func (h *Demo) GetV1Demo(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("Hello World!"))
+func (h *Demo) GetV1Demo(_ context.Context, _ api.GetV1DemoRequestObject) (api.GetV1DemoResponseObject, error) {
+	return api.GetV1Demo200TextResponse("Hello World!"), nil
 }
 
 // Demo implements the demo OpenAPI spec.
@@ -36,3 +39,14 @@ func New(opts ...Opt) *Demo {
 	}
 	return r
 }
+
+// Handler returns the Demo API as a server of the supplied demo spec, with the
+// shared strict middleware and problem details for errors.
+func (h *Demo) Handler(swagger *openapi3.T) api.ServerInterface {
+	errs := strict.NewErrorHandler(strict.ErrorHandlerWithLogger(h.log))
+	mws := strict.Middlewares[api.StrictMiddlewareFunc](strict.Authenticated(swagger), strict.Logging(h.log))
+	return api.NewStrictHandlerWithOptions(h, mws, api.StrictHTTPServerOptions{
+		RequestErrorHandlerFunc:  errs.Request,
+		ResponseErrorHandlerFunc: errs.Response,
+	})
+}
@@ -0,0 +1,49 @@
+package entities
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/go-cmp/cmp"
+
+	api "github.com/upbound/build-submodule-demo/internal/api/demo"
+)
+
+// debugLogger records debug messages and the values of their logger.
+type debugLogger struct {
+	logging.Logger
+	values []any
+	debug  *[][]any
+}
+
+func (l debugLogger) Debug(msg string, _ ...any) {
+	*l.debug = append(*l.debug, append([]any{msg}, l.values...))
+}
+
+func (l debugLogger) WithValues(kv ...any) logging.Logger {
+	return debugLogger{Logger: l.Logger, values: append(append([]any{}, l.values...), kv...), debug: l.debug}
+}
+
+func TestHandler(t *testing.T) {
+	swagger, err := api.GetSwagger()
+	if err != nil {
+		t.Fatalf("GetSwagger(): %v", err)
+	}
+	swagger.Security = openapi3.SecurityRequirements{{"session": {}}}
+
+	var got [][]any
+	l := debugLogger{Logger: logging.NewNopLogger(), debug: &got}
+	rr := httptest.NewRecorder()
+	New(WithLogger(l)).Handler(swagger).GetV1Demo(rr, httptest.NewRequest(http.MethodGet, "/v1/demo", nil))
+
+	if diff := cmp.Diff(http.StatusUnauthorized, rr.Code); diff != "" {
+		t.Errorf("\nHandler(...): -want status, +got status:\n%s", diff)
+	}
+	want := [][]any{{"operation failed", "requestID", "", "operation", "GetV1Demo"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("\nHandler(...): requests rejected by other middleware should be logged with their operation: -want, +got:\n%s", diff)
+	}
+}
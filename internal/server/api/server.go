@@ -3,32 +3,138 @@ package api
 import (
 	"fmt"
 	"net/http"
-	"time"
 
-	oapimiddleware "github.com/deepmap/oapi-codegen/pkg/chi-middleware"
 	oapifilter "github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/go-chi/chi/v5"
 	chimid "github.com/go-chi/chi/v5/middleware"
+	"github.com/pkg/errors"
 
 	"github.com/upbound/build-submodule-demo/internal"
 	apidemo "github.com/upbound/build-submodule-demo/internal/api/demo"
+	"github.com/upbound/build-submodule-demo/internal/generics"
 	// "github.com/upbound/build-submodule-demo/internal/client/auth"
+	"github.com/upbound/build-submodule-demo/internal/idempotency"
 	"github.com/upbound/build-submodule-demo/internal/log"
+	"github.com/upbound/build-submodule-demo/internal/ratelimit"
+	"github.com/upbound/build-submodule-demo/internal/reporting"
 	srvdemo "github.com/upbound/build-submodule-demo/internal/server/api/demo"
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
 	"github.com/upbound/build-submodule-demo/internal/server/metrics/otel"
-	// "github.com/upbound/build-submodule-demo/internal/server/middleware"
+	"github.com/upbound/build-submodule-demo/internal/server/middleware"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+	"github.com/upbound/build-submodule-demo/internal/server/spec"
 )
 
-// Server serves the Entities API.
-func Server(opts internal.ServiceOptions) (*http.Server, error) {
+// routeGroupDemo is the name of the demo route group in per-route throttle
+// limits.
+const routeGroupDemo = "demo"
+
+// routeGroups are the route groups that per-route throttle limits may be set
+// for.
+var routeGroups = []string{routeGroupDemo}
+
+const (
+	errUnknownRouteGroup = "unknown throttle route group %q, must be one of %v"
+	errThrottleLimit     = "throttle limit must be greater than zero"
+	errRouteLimit        = "throttle limit of route group %q must be greater than zero"
+	errThrottleBacklog   = "throttle backlog limit must not be negative"
+)
+
+// Server serves the Entities API. Requests are shed by the supplied adaptive
+// limiter if it is not nil. Cross-origin requests are handled by the supplied
+// CORS middleware, which allows no origins if nil.
+func Server(opts internal.ServiceOptions, limiter *middleware.AdaptiveLimiter, cors *middleware.CORS) (*http.Server, error) {
+	proxies, err := forwarded.ParseCIDRs(opts.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateThrottle(opts.ThrottleOptions); err != nil {
+		return nil, err
+	}
+
+	// Validate demo requests against OpenAPIv3 spec.
+	repoSwagger, err := apidemo.GetSwagger()
+	if err != nil {
+		return nil, err
+	}
+	repoSwagger.Servers = nil
+
+	limits, err := middleware.NewRouteLimits(repoSwagger,
+		middleware.RouteLimitsWithMaxBodyBytes(opts.APITimeouts.MaxBodyBytes),
+		middleware.RouteLimitsWithTimeout(opts.APITimeouts.RequestTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var security *middleware.SecurityHeaders
+	if opts.SecurityHeaders {
+		security, err = middleware.NewSecurityHeaders(repoSwagger,
+			middleware.SecurityHeadersWithHSTS(opts.HSTSMaxAge, opts.HSTSIncludeSubdomains, opts.HSTSPreload),
+			middleware.SecurityHeadersWithReferrerPolicy(opts.ReferrerPolicy),
+			middleware.SecurityHeadersWithFrameOptions(opts.FrameOptions),
+			middleware.SecurityHeadersWithContentSecurityPolicy(opts.ContentSecurityPolicy),
+			middleware.SecurityHeadersWithNoStore(opts.NoStoreAuthenticated),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reporter, err := reporting.New(opts.ErrorReportingOptions)
+	if err != nil {
+		return nil, err
+	}
+
 	r := chi.NewRouter()
-	r.Use(chimid.RequestLogger(&log.Formatter{Log: opts.Log}))
+	r.NotFound(problem.NotFound)
+	r.MethodNotAllowed(problem.MethodNotAllowed)
+	r.Use(middleware.RequestID(opts.RequestIDHeader, opts.Log))
+	r.Use(middleware.Forwarded(proxies))
+	r.Use(otel.Tracing)
+	r.Use(chimid.RequestLogger(log.NewAccessFormatter(opts.Log, opts.AccessLogOptions)))
+	r.Use(middleware.NewRecoverer(
+		middleware.RecovererWithLogger(opts.Log),
+		middleware.RecovererWithReporter(reporter),
+		middleware.RecovererWithRedactParams(opts.AccessLogRedactParams...),
+	).Recover)
+	if security != nil {
+		r.Use(security.Handler)
+	}
+	// Preflight requests are answered before they reach request validation
+	// and authentication.
+	if cors != nil {
+		r.Use(cors.Handler)
+	}
 	r.Use(chimid.RedirectSlashes)
-	r.Use(otel.Middleware)
-	r.Use(chimid.Compress(5))
-	// TODO(hasheddan): make this configurable and consider limiting
-	// connections, not just requests.
-	r.Use(chimid.Throttle(400))
+	r.Use(otel.Middleware(opts.MetricsSemconv))
+	if opts.EnableGZip {
+		r.Use(middleware.NewCompressor(
+			middleware.CompressorWithEncodings(opts.CompressionEncodings...),
+			middleware.CompressorWithMinSize(opts.CompressionMinSize),
+			middleware.CompressorWithContentTypes(opts.CompressionContentTypes...),
+		).Handler)
+	}
+	// URLs are rewritten before responses are compressed.
+	r.Use(middleware.NewRewriter(
+		middleware.RewriterWithLogger(opts.Log),
+		middleware.RewriterWithPathPrefix(opts.PathPrefix),
+		middleware.RewriterWithJSONFields(opts.RewriteJSONFields...),
+	).Rewrite)
+	if limiter != nil {
+		r.Use(limiter.Limit)
+	}
+	r.Use(middleware.ThrottleWithOpts(throttleOpts(opts.ThrottleOptions, opts.ThrottleLimit)))
+	// Bodies are limited before they are decompressed, and the request
+	// deadline starts once the request is admitted.
+	r.Use(limits.Handler)
+	// Request bodies are decompressed before they are validated.
+	if opts.RequestDecompression {
+		r.Use(middleware.NewDecompressor(
+			middleware.DecompressorWithMaxSize(opts.RequestMaxDecompressedSize),
+			middleware.DecompressorWithMaxRatio(opts.RequestMaxCompressionRatio),
+		).Handler)
+	}
 
 	// For demo
 	// // The auth manager is responsible for all authentication and authorization
@@ -37,34 +143,103 @@ func Server(opts internal.ServiceOptions) (*http.Server, error) {
 
 	// Add Demo API server to router.
 
-	// Validate demo requests against OpenAPIv3 spec.
-	repoSwagger, err := apidemo.GetSwagger()
+	rateLimits, err := middleware.RateLimitsFromOptions(opts.RateLimitOptions)
 	if err != nil {
 		return nil, err
 	}
-	repoSwagger.Servers = nil
+
+	if opts.OpenAPISpec {
+		sh, err := spec.New(repoSwagger, append(spec.OptsFromOptions(opts.OpenAPIOptions), spec.WithPathPrefix(opts.PathPrefix))...)
+		if err != nil {
+			return nil, err
+		}
+		sh.Mount(r)
+	}
 
 	// Override demo authentication because validator handles incorrectly.
-	repoValidOpts := &oapimiddleware.Options{}
+	// Invalid demo requests are rejected with problem details; registry-style
+	// route groups should use the middleware.FormatOCI format instead.
+	repoValidOpts := &middleware.Options{Format: middleware.FormatProblem}
 	repoValidOpts.Options.AuthenticationFunc = oapifilter.NoopAuthenticationFunc
 
 	// Add demo API server to router.
 	r.Group(func(r chi.Router) {
-		r.Use(oapimiddleware.OapiRequestValidatorWithOptions(repoSwagger, repoValidOpts))
+		if opts.CSRF {
+			csrfOpts := []middleware.CSRFOpt{middleware.CSRFWithLogger(opts.Log)}
+			if cors != nil {
+				// Origins allowed to make credentialed cross-origin requests
+				// are trusted to make state-changing ones.
+				csrfOpts = append(csrfOpts, middleware.CSRFWithOriginPolicy(cors))
+			}
+			r.Use(middleware.NewCSRF(csrfOpts...).Protect)
+		}
+		if limit, ok := opts.ThrottleRouteLimits[routeGroupDemo]; ok {
+			r.Use(middleware.ThrottleWithOpts(throttleOpts(opts.ThrottleOptions, limit)))
+		}
+		r.Use(middleware.RequestValidatorWithOptions(repoSwagger, repoValidOpts))
+		// Conditional requests are answered before response validation, as
+		// 304 and 412 responses are not declared by every operation.
+		if opts.ETags {
+			r.Use(middleware.NewETagger().Handler)
+		}
+		r.Use(middleware.ResponseValidatorWithOptions(repoSwagger, middleware.ResponseValidationMode(opts.ResponseValidation, opts.DevMode), opts.Log, repoValidOpts))
 
 		// Remove for demo
 		// // Authentication is required on all routes.
 		// r.Use(middleware.NewAuthN(a, middleware.AuthNWithLogger(opts.Log)).Required)
 
+		if opts.RateLimit {
+			r.Use(middleware.NewRateLimiter(ratelimit.NewMemoryStore(), rateLimits, middleware.RateLimiterWithLogger(opts.Log)).Limit)
+		}
+		if opts.Idempotency {
+			r.Use(middleware.NewIdempotency(idempotency.NewMemoryStore(),
+				middleware.IdempotencyWithLogger(opts.Log),
+				middleware.IdempotencyWithTTL(opts.IdempotencyTTL),
+				middleware.IdempotencyWithLockTimeout(opts.IdempotencyLockTimeout),
+			).Handler)
+		}
+
 		handlers := srvdemo.New(srvdemo.WithLogger(opts.Log))
-		apidemo.HandlerFromMux(handlers, r)
+		apidemo.HandlerFromMux(handlers.Handler(repoSwagger), r)
 	})
 
 	return &http.Server{
 		Handler:           r,
 		Addr:              fmt.Sprintf(":%d", opts.APIPort),
-		ReadTimeout:       5 * time.Second,
-		ReadHeaderTimeout: 5 * time.Second,
-		WriteTimeout:      10 * time.Second,
+		ReadTimeout:       opts.APITimeouts.ReadTimeout,
+		ReadHeaderTimeout: opts.APITimeouts.ReadHeaderTimeout,
+		WriteTimeout:      opts.APITimeouts.WriteTimeout,
+		IdleTimeout:       opts.APITimeouts.IdleTimeout,
 	}, nil
 }
+
+// validateThrottle returns an error if the supplied options would configure
+// throttle middleware that rejects every request, or that cannot be built.
+func validateThrottle(opts internal.ThrottleOptions) error {
+	if opts.ThrottleLimit < 1 {
+		return errors.New(errThrottleLimit)
+	}
+	if opts.ThrottleBacklogLimit < 0 {
+		return errors.New(errThrottleBacklog)
+	}
+	for group, limit := range opts.ThrottleRouteLimits {
+		if !generics.Contains(routeGroups, group) {
+			return errors.Errorf(errUnknownRouteGroup, group, routeGroups)
+		}
+		if limit < 1 {
+			return errors.Errorf(errRouteLimit, group)
+		}
+	}
+	return nil
+}
+
+// throttleOpts builds throttle options with the supplied concurrency limit.
+func throttleOpts(opts internal.ThrottleOptions, limit int) middleware.ThrottleOpts {
+	return middleware.ThrottleOpts{
+		Limit:          limit,
+		BacklogLimit:   opts.ThrottleBacklogLimit,
+		BacklogTimeout: opts.ThrottleBacklogTimeout,
+		RetryAfter:     opts.ThrottleRetryAfter,
+		ExemptPaths:    opts.ThrottleExemptPaths,
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal"
+)
+
+func TestValidateThrottle(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		opts   internal.ThrottleOptions
+		want   error
+	}{
+		"Valid": {
+			reason: "Positive limits for known route groups should be valid.",
+			opts:   internal.ThrottleOptions{ThrottleLimit: 1, ThrottleRouteLimits: map[string]int{routeGroupDemo: 1}},
+		},
+		"ZeroLimit": {
+			reason: "A zero limit would reject every request.",
+			opts:   internal.ThrottleOptions{},
+			want:   errors.New(errThrottleLimit),
+		},
+		"NegativeBacklog": {
+			reason: "A backlog cannot hold a negative number of requests.",
+			opts:   internal.ThrottleOptions{ThrottleLimit: 1, ThrottleBacklogLimit: -1},
+			want:   errors.New(errThrottleBacklog),
+		},
+		"UnknownRouteGroup": {
+			reason: "Limits of unknown route groups would never apply.",
+			opts:   internal.ThrottleOptions{ThrottleLimit: 1, ThrottleRouteLimits: map[string]int{"nope": 1}},
+			want:   errors.Errorf(errUnknownRouteGroup, "nope", routeGroups),
+		},
+		"ZeroRouteLimit": {
+			reason: "A zero route group limit would reject every request to the group.",
+			opts:   internal.ThrottleOptions{ThrottleLimit: 1, ThrottleRouteLimits: map[string]int{routeGroupDemo: 0}},
+			want:   errors.Errorf(errRouteLimit, routeGroupDemo),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateThrottle(tc.opts)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nvalidateThrottle(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Package conditional implements HTTP validators and conditional requests as
+// described by RFC 9110, for use by handlers and middleware.
+package conditional
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errPrecondition = "the resource does not match the request preconditions"
+)
+
+// Validator headers and the request headers that refer to them.
+const (
+	HeaderETag              = "ETag"
+	HeaderLastModified      = "Last-Modified"
+	HeaderIfMatch           = "If-Match"
+	HeaderIfNoneMatch       = "If-None-Match"
+	HeaderIfModifiedSince   = "If-Modified-Since"
+	HeaderIfUnmodifiedSince = "If-Unmodified-Since"
+)
+
+// HashETag returns a strong entity tag for a representation, derived from a
+// hash of its content.
+func HashETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// VersionETag returns a strong entity tag for a representation, derived from
+// the version of the resource it represents, e.g. a generation or revision.
+// The version must change whenever the representation does.
+func VersionETag(version string) string {
+	return `"` + strings.ReplaceAll(version, `"`, "") + `"`
+}
+
+// Evaluate evaluates the preconditions of a request against the current
+// entity tag and modification time of the target resource, either of which
+// may be empty. It returns 0 if the request should be processed, or the
+// status it should be answered with instead: 304 Not Modified or 412
+// Precondition Failed.
+func Evaluate(r *http.Request, etag string, modified time.Time) int {
+	switch im := r.Header.Get(HeaderIfMatch); {
+	case im != "":
+		if !match(im, etag, true) {
+			return http.StatusPreconditionFailed
+		}
+	case !modified.IsZero():
+		if t, ok := parseTime(r.Header.Get(HeaderIfUnmodifiedSince)); ok && modified.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	switch inm := r.Header.Get(HeaderIfNoneMatch); {
+	case inm != "":
+		if match(inm, etag, false) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	case safe && !modified.IsZero():
+		if t, ok := parseTime(r.Header.Get(HeaderIfModifiedSince)); ok && !modified.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// Check sets the validators of the target resource on the response and
+// evaluates the preconditions of the request against them. If the request
+// should not be processed it writes a 304 Not Modified response or a 412
+// Precondition Failed problem and returns false. Handlers should call it
+// before acting on a request, e.g.
+//
+//	if !conditional.Check(w, r, conditional.VersionETag(v), modified) {
+//		return
+//	}
+func Check(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	SetValidators(w, etag, modified)
+	status := Evaluate(r, etag, modified)
+	if status == 0 {
+		return true
+	}
+	Respond(w, r, status)
+	return false
+}
+
+// Respond writes the response to a request that Evaluate determined should not
+// be processed: a not modified response without a body, or a precondition
+// failed problem. The validators of a not modified response are kept, while
+// those of a precondition failed problem are removed, as the problem is not a
+// representation of the resource.
+func Respond(w http.ResponseWriter, r *http.Request, status int) {
+	h := w.Header()
+	h.Del("Content-Length")
+	if status == http.StatusNotModified {
+		h.Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Del(HeaderETag)
+	h.Del(HeaderLastModified)
+	problem.Error(w, r, http.StatusPreconditionFailed, errPrecondition)
+}
+
+// SetValidators sets the ETag and Last-Modified headers of a response, if
+// known.
+func SetValidators(w http.ResponseWriter, etag string, modified time.Time) {
+	if etag != "" {
+		w.Header().Set(HeaderETag, etag)
+	}
+	if !modified.IsZero() {
+		w.Header().Set(HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// match reports whether a list of entity tags, or *, matches an entity tag
+// using strong or weak comparison.
+func match(list, etag string, strong bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return etag != ""
+	}
+	if etag == "" || (strong && isWeak(etag)) {
+		return false
+	}
+	for _, t := range strings.Split(list, ",") {
+		t = strings.TrimSpace(t)
+		if strong && isWeak(t) {
+			continue
+		}
+		if opaque(t) == opaque(etag) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWeak(etag string) bool {
+	return strings.HasPrefix(etag, "W/")
+}
+
+// opaque returns the opaque tag of an entity tag, without the weak prefix.
+func opaque(etag string) string {
+	return strings.TrimPrefix(etag, "W/")
+}
+
+func parseTime(v string) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(v)
+	return t, err == nil
+}
@@ -0,0 +1,101 @@
+package conditional
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEvaluate(t *testing.T) {
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	type arguments struct {
+		method   string
+		headers  map[string]string
+		etag     string
+		modified time.Time
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   int
+	}{
+		"Unconditional": {
+			reason: "Requests without preconditions should be processed.",
+			args:   arguments{method: http.MethodGet, etag: `"a"`},
+			want:   0,
+		},
+		"IfNoneMatchMatches": {
+			reason: "GET requests for a matching ETag should not be modified.",
+			args:   arguments{method: http.MethodGet, headers: map[string]string{HeaderIfNoneMatch: `"b", W/"a"`}, etag: `"a"`},
+			want:   http.StatusNotModified,
+		},
+		"IfNoneMatchDiffers": {
+			reason: "GET requests for a different ETag should be processed.",
+			args:   arguments{method: http.MethodGet, headers: map[string]string{HeaderIfNoneMatch: `"b"`}, etag: `"a"`},
+			want:   0,
+		},
+		"IfNoneMatchUnsafe": {
+			reason: "Unsafe requests with a matching If-None-Match should fail.",
+			args:   arguments{method: http.MethodPut, headers: map[string]string{HeaderIfNoneMatch: "*"}, etag: `"a"`},
+			want:   http.StatusPreconditionFailed,
+		},
+		"IfMatchMatches": {
+			reason: "Writes to a resource with a matching ETag should be processed.",
+			args:   arguments{method: http.MethodPut, headers: map[string]string{HeaderIfMatch: `"a"`}, etag: `"a"`},
+			want:   0,
+		},
+		"IfMatchDiffers": {
+			reason: "Writes to a resource with a different ETag should fail.",
+			args:   arguments{method: http.MethodPut, headers: map[string]string{HeaderIfMatch: `"b"`}, etag: `"a"`},
+			want:   http.StatusPreconditionFailed,
+		},
+		"IfMatchWeak": {
+			reason: "If-Match should use strong comparison.",
+			args:   arguments{method: http.MethodPut, headers: map[string]string{HeaderIfMatch: `W/"a"`}, etag: `"a"`},
+			want:   http.StatusPreconditionFailed,
+		},
+		"IfMatchMissing": {
+			reason: "Writes with If-Match: * to a resource that does not exist should fail.",
+			args:   arguments{method: http.MethodPut, headers: map[string]string{HeaderIfMatch: "*"}},
+			want:   http.StatusPreconditionFailed,
+		},
+		"NotModifiedSince": {
+			reason: "GET requests for a resource not modified since should not be modified.",
+			args:   arguments{method: http.MethodGet, headers: map[string]string{HeaderIfModifiedSince: modified.Format(http.TimeFormat)}, modified: modified.Add(time.Millisecond)},
+			want:   http.StatusNotModified,
+		},
+		"ModifiedSince": {
+			reason: "GET requests for a resource modified since should be processed.",
+			args:   arguments{method: http.MethodGet, headers: map[string]string{HeaderIfModifiedSince: modified.Format(http.TimeFormat)}, modified: modified.Add(time.Second)},
+			want:   0,
+		},
+		"IfNoneMatchPrecedence": {
+			reason: "If-Modified-Since should be ignored when If-None-Match is present.",
+			args: arguments{method: http.MethodGet, headers: map[string]string{
+				HeaderIfNoneMatch:     `"b"`,
+				HeaderIfModifiedSince: modified.Format(http.TimeFormat),
+			}, etag: `"a"`, modified: modified},
+			want: 0,
+		},
+		"ModifiedAfterUnmodifiedSince": {
+			reason: "Writes to a resource modified since If-Unmodified-Since should fail.",
+			args:   arguments{method: http.MethodDelete, headers: map[string]string{HeaderIfUnmodifiedSince: modified.Format(http.TimeFormat)}, modified: modified.Add(time.Hour)},
+			want:   http.StatusPreconditionFailed,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.args.method, "/", nil)
+			for k, v := range tc.args.headers {
+				r.Header.Set(k, v)
+			}
+			got := Evaluate(r, tc.args.etag, tc.args.modified)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nEvaluate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
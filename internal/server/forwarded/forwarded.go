@@ -0,0 +1,251 @@
+// Package forwarded determines the client-facing properties of requests that
+// reach the service through reverse proxies.
+package forwarded
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errParseCIDR = "failed to parse trusted proxy %q"
+)
+
+// Forwarding headers.
+const (
+	HeaderForwarded        = "Forwarded"
+	HeaderXForwardedFor    = "X-Forwarded-For"
+	HeaderXForwardedHost   = "X-Forwarded-Host"
+	HeaderXForwardedProto  = "X-Forwarded-Proto"
+	HeaderXForwardedPrefix = "X-Forwarded-Prefix"
+)
+
+// Info describes a request as it was made by the client.
+type Info struct {
+	// ClientIP is the address of the client.
+	ClientIP string
+
+	// Scheme is the scheme the client used, http or https.
+	Scheme string
+
+	// Host is the host the client requested, including the port if any.
+	Host string
+
+	// Prefix is the path prefix a proxy stripped from the request, if any.
+	Prefix string
+}
+
+type ctxkey int
+
+const infoKey ctxkey = 0
+
+// NewContext returns a context carrying the supplied info.
+func NewContext(ctx context.Context, i Info) context.Context {
+	return context.WithValue(ctx, infoKey, i)
+}
+
+// FromContext extracts the info from the supplied context.
+func FromContext(ctx context.Context) (Info, bool) {
+	i, ok := ctx.Value(infoKey).(Info)
+	return i, ok
+}
+
+// FromRequest returns the info stored in the request context, falling back
+// to the properties of the request itself.
+func FromRequest(r *http.Request) Info {
+	if i, ok := FromContext(r.Context()); ok {
+		return i
+	}
+	return direct(r)
+}
+
+// ParseCIDRs parses trusted proxy CIDRs. Bare IP addresses are trusted as a
+// single address.
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, errors.Errorf(errParseCIDR, c)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, errors.Wrapf(err, errParseCIDR, c)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// Parse determines the client-facing properties of a request. Forwarding
+// headers are only honored if the request was received from a trusted proxy,
+// in which case the client is the rightmost address in the forwarding chain
+// that is not itself a trusted proxy. The RFC 7239 Forwarded header takes
+// precedence over the X-Forwarded-* headers. The path prefix is always taken
+// from X-Forwarded-Prefix, which has no Forwarded equivalent.
+func Parse(r *http.Request, trusted []*net.IPNet) Info {
+	i := direct(r)
+	if !isTrusted(i.ClientIP, trusted) {
+		return i
+	}
+	if p := last(r.Header.Values(HeaderXForwardedPrefix)); strings.HasPrefix(p, "/") {
+		i.Prefix = strings.TrimSuffix(p, "/")
+	}
+	if vs := r.Header.Values(HeaderForwarded); len(vs) > 0 {
+		return parseForwarded(i, vs, trusted)
+	}
+	return parseXForwarded(i, r.Header, trusted)
+}
+
+// direct returns the info of a request as received.
+func direct(r *http.Request) Info {
+	ip := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(ip); err == nil {
+		ip = h
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return Info{ClientIP: ip, Scheme: scheme, Host: r.Host}
+}
+
+// parseForwarded walks the elements of RFC 7239 Forwarded headers from the
+// nearest proxy. The proto and host of the element added by the outermost
+// trusted proxy describe the request made by the client.
+func parseForwarded(i Info, values []string, trusted []*net.IPNet) Info {
+	elems := []map[string]string{}
+	for _, v := range values {
+		for _, e := range splitQuoted(v, ',') {
+			pairs := map[string]string{}
+			for _, p := range splitQuoted(e, ';') {
+				k, v, ok := strings.Cut(p, "=")
+				if !ok {
+					continue
+				}
+				pairs[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+			}
+			elems = append(elems, pairs)
+		}
+	}
+	for n := len(elems) - 1; n >= 0; n-- {
+		e := elems[n]
+		if p := strings.ToLower(e["proto"]); p == "http" || p == "https" {
+			i.Scheme = p
+		}
+		if h := e["host"]; h != "" {
+			i.Host = h
+		}
+		ip := forwardedNode(e["for"])
+		if ip == "" {
+			break
+		}
+		i.ClientIP = ip
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+	return i
+}
+
+// parseXForwarded determines the client from X-Forwarded-For and takes the
+// scheme and host from the values appended by the nearest proxy.
+func parseXForwarded(i Info, h http.Header, trusted []*net.IPNet) Info {
+	if p := strings.ToLower(last(h.Values(HeaderXForwardedProto))); p == "http" || p == "https" {
+		i.Scheme = p
+	}
+	if host := last(h.Values(HeaderXForwardedHost)); host != "" {
+		i.Host = host
+	}
+	fors := []string{}
+	for _, v := range h.Values(HeaderXForwardedFor) {
+		fors = append(fors, strings.Split(v, ",")...)
+	}
+	for n := len(fors) - 1; n >= 0; n-- {
+		ip := strings.TrimSpace(fors[n])
+		if net.ParseIP(ip) == nil {
+			break
+		}
+		i.ClientIP = ip
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+	return i
+}
+
+// forwardedNode returns the IP of a Forwarded for= node, or an empty string if
+// it is unknown or obfuscated.
+func forwardedNode(node string) string {
+	if strings.HasPrefix(node, "[") {
+		end := strings.Index(node, "]")
+		if end < 0 {
+			return ""
+		}
+		node = node[1:end]
+	} else if h, _, err := net.SplitHostPort(node); err == nil {
+		node = h
+	}
+	if net.ParseIP(node) == nil {
+		return ""
+	}
+	return node
+}
+
+func isTrusted(ip string, trusted []*net.IPNet) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// last returns the last of a list of comma separated values.
+func last(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	v := values[len(values)-1]
+	if i := strings.LastIndex(v, ","); i >= 0 {
+		v = v[i+1:]
+	}
+	return strings.TrimSpace(v)
+}
+
+// splitQuoted splits s by sep outside of quoted strings.
+func splitQuoted(s string, sep rune) []string {
+	parts := []string{}
+	quoted := false
+	start := 0
+	for n, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:n])
+			start = n + 1
+		}
+	}
+	return append(parts, s[start:])
+}
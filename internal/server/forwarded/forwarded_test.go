@@ -0,0 +1,104 @@
+package forwarded
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	type arguments struct {
+		remote  string
+		headers http.Header
+	}
+	type want struct {
+		info Info
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Direct": {
+			reason: "A request without forwarding headers should be described as received.",
+			args: arguments{
+				remote: "203.0.113.7:5000",
+			},
+			want: want{
+				info: Info{ClientIP: "203.0.113.7", Scheme: "http", Host: "api.internal"},
+			},
+		},
+		"Untrusted": {
+			reason: "Forwarding headers from an untrusted peer should be ignored.",
+			args: arguments{
+				remote: "203.0.113.7:5000",
+				headers: http.Header{
+					HeaderXForwardedFor:   {"198.51.100.1"},
+					HeaderXForwardedProto: {"https"},
+					HeaderXForwardedHost:  {"evil.example.org"},
+				},
+			},
+			want: want{
+				info: Info{ClientIP: "203.0.113.7", Scheme: "http", Host: "api.internal"},
+			},
+		},
+		"XForwarded": {
+			reason: "The client should be the rightmost untrusted address in X-Forwarded-For.",
+			args: arguments{
+				remote: "10.0.0.2:5000",
+				headers: http.Header{
+					HeaderXForwardedFor:    {"192.0.2.66, 198.51.100.1", "10.0.0.3"},
+					HeaderXForwardedProto:  {"https"},
+					HeaderXForwardedHost:   {"api.example.com"},
+					HeaderXForwardedPrefix: {"/demo/"},
+				},
+			},
+			want: want{
+				info: Info{ClientIP: "198.51.100.1", Scheme: "https", Host: "api.example.com", Prefix: "/demo"},
+			},
+		},
+		"Forwarded": {
+			reason: "The Forwarded header should take precedence and spoofed elements should be ignored.",
+			args: arguments{
+				remote: "10.0.0.2:5000",
+				headers: http.Header{
+					HeaderForwarded:      {`for=192.0.2.66;host=evil.example.org, for="[2001:db8::1]:4711";proto=https;host=api.example.com`},
+					HeaderXForwardedHost: {"other.example.com"},
+				},
+			},
+			want: want{
+				info: Info{ClientIP: "2001:db8::1", Scheme: "https", Host: "api.example.com"},
+			},
+		},
+		"ForwardedObfuscated": {
+			reason: "An obfuscated client should leave the client IP as the nearest known address.",
+			args: arguments{
+				remote: "10.0.0.2:5000",
+				headers: http.Header{
+					HeaderForwarded: {`for=_hidden;proto=https;host=api.example.com`},
+				},
+			},
+			want: want{
+				info: Info{ClientIP: "10.0.0.2", Scheme: "https", Host: "api.example.com"},
+			},
+		},
+	}
+	trusted, err := ParseCIDRs([]string{"10.0.0.0/8", "::1"})
+	if err != nil {
+		t.Fatalf("ParseCIDRs(...): unexpected error: %v", err)
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequestWithContext(context.Background(), "GET", "http://api.internal/v1/demo", nil)
+			req.RemoteAddr = tc.args.remote
+			for k, v := range tc.args.headers {
+				req.Header[k] = v
+			}
+			if diff := cmp.Diff(tc.want.info, Parse(req, trusted)); diff != "" {
+				t.Errorf("\n%s\nParse(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
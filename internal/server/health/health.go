@@ -1,19 +1,23 @@
 package health
 
 import (
-	"net/http"
+	"context"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+
+	api "github.com/upbound/build-submodule-demo/internal/api/health"
+	"github.com/upbound/build-submodule-demo/internal/server/strict"
 )
 
 // GetLiveness gets the servic liveness.
-func (h *Probes) GetLiveness(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
+func (h *Probes) GetLiveness(_ context.Context, _ api.GetLivenessRequestObject) (api.GetLivenessResponseObject, error) {
+	return api.GetLiveness200Response{}, nil
 }
 
 // GetReadiness gets the build-submodule-demo readiness.
-func (h *Probes) GetReadiness(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
+func (h *Probes) GetReadiness(_ context.Context, _ api.GetReadinessRequestObject) (api.GetReadinessResponseObject, error) {
+	return api.GetReadiness200Response{}, nil
 }
 
 // Probes indicates the health of a build-submodule-demo.
@@ -42,3 +46,14 @@ func New(opts ...Opt) *Probes {
 	}
 	return p
 }
+
+// Handler returns the probes as a server of the supplied health spec, with
+// the shared strict middleware and problem details for errors.
+func (p *Probes) Handler(swagger *openapi3.T) api.ServerInterface {
+	errs := strict.NewErrorHandler(strict.ErrorHandlerWithLogger(p.log))
+	mws := strict.Middlewares[api.StrictMiddlewareFunc](strict.Authenticated(swagger), strict.Logging(p.log))
+	return api.NewStrictHandlerWithOptions(p, mws, api.StrictHTTPServerOptions{
+		RequestErrorHandlerFunc:  errs.Request,
+		ResponseErrorHandlerFunc: errs.Response,
+	})
+}
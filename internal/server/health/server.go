@@ -3,28 +3,59 @@ package health
 import (
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/go-chi/chi/v5"
 	chimid "github.com/go-chi/chi/v5/middleware"
 
 	"github.com/upbound/build-submodule-demo/internal"
 	api "github.com/upbound/build-submodule-demo/internal/api/health"
+	"github.com/upbound/build-submodule-demo/internal/log"
+	"github.com/upbound/build-submodule-demo/internal/server/middleware"
+	"github.com/upbound/build-submodule-demo/internal/server/spec"
 )
 
 // Server is a liveness and readiness server.
 func Server(opts internal.CommonOptions) (*http.Server, error) {
+	swagger, err := api.GetSwagger()
+	if err != nil {
+		return nil, err
+	}
+	swagger.Servers = nil
+	limits, err := middleware.NewRouteLimits(swagger,
+		middleware.RouteLimitsWithMaxBodyBytes(opts.PrivateTimeouts.MaxBodyBytes),
+		middleware.RouteLimitsWithTimeout(opts.PrivateTimeouts.RequestTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	r := chi.NewRouter()
+	r.Use(chimid.RequestLogger(log.NewAccessFormatter(opts.Log, opts.AccessLogOptions)))
 	r.Use(chimid.RedirectSlashes)
-	r.Use(chimid.Compress(5))
+	if opts.EnableGZip {
+		r.Use(middleware.NewCompressor(
+			middleware.CompressorWithEncodings(opts.CompressionEncodings...),
+			middleware.CompressorWithMinSize(opts.CompressionMinSize),
+			middleware.CompressorWithContentTypes(opts.CompressionContentTypes...),
+		).Handler)
+	}
+	r.Use(limits.Handler)
 
-	api.HandlerFromMux(New(WithLogger(opts.Log)), r)
+	if opts.OpenAPISpec {
+		sh, err := spec.New(swagger, spec.OptsFromOptions(opts.OpenAPIOptions)...)
+		if err != nil {
+			return nil, err
+		}
+		sh.Mount(r)
+	}
+	api.HandlerFromMux(New(WithLogger(opts.Log)).Handler(swagger), r)
 
 	return &http.Server{
 		Handler:           r,
 		Addr:              fmt.Sprintf(":%d", opts.PrivatePort),
-		ReadHeaderTimeout: 5 * time.Second,
-		ReadTimeout:       5 * time.Second,
-		WriteTimeout:      10 * time.Second,
+		ReadTimeout:       opts.PrivateTimeouts.ReadTimeout,
+		ReadHeaderTimeout: opts.PrivateTimeouts.ReadHeaderTimeout,
+		WriteTimeout:      opts.PrivateTimeouts.WriteTimeout,
+		IdleTimeout:       opts.PrivateTimeouts.IdleTimeout,
 	}, nil
 }
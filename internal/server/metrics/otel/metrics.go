@@ -2,6 +2,7 @@ package otel
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"go.opentelemetry.io/otel/metric"
 
 	"github.com/upbound/build-submodule-demo/internal/generics"
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
 )
 
 const (
@@ -20,6 +22,26 @@ const (
 	Rejected  = "rejected"
 )
 
+// Semantic convention modes for HTTP server metrics.
+const (
+	// SemconvStable emits instruments and attributes following the stable
+	// OpenTelemetry HTTP semantic conventions.
+	SemconvStable = "stable"
+	// SemconvDup emits both the stable and legacy instruments and attributes
+	// to allow dashboards and alerts to be migrated.
+	SemconvDup = "dup"
+	// SemconvLegacy emits only the legacy instruments and attributes.
+	SemconvLegacy = "legacy"
+)
+
+// HTTPServerRequestDuration is the name of the stable HTTP server request
+// duration instrument.
+const HTTPServerRequestDuration = "http.server.request.duration"
+
+// HTTPServerRequestDurationBuckets are the explicit bucket boundaries, in
+// seconds, recommended for the stable HTTP server request duration.
+var HTTPServerRequestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10}
+
 var (
 	meter = opentel.GetMeterProvider().Meter("build-submodule-demo")
 
@@ -35,27 +57,79 @@ var (
 		metric.WithDescription("Time between receiving and responding to an http request."),
 		metric.WithUnit(string(metricdata.UnitMilliseconds))))
 
+	serverReqDuration = generics.Must(meter.Float64Histogram(HTTPServerRequestDuration,
+		metric.WithDescription("Duration of HTTP server requests."),
+		metric.WithUnit("s")))
+
+	serverActiveReqs = generics.Must(meter.Int64UpDownCounter("http.server.active_requests",
+		metric.WithDescription("Number of active HTTP server requests."),
+		metric.WithUnit("{request}")))
+
+	panicsRecovered = generics.Must(meter.Int64Counter("http.server.panics",
+		metric.WithDescription("Total number of panics recovered while handling http requests."),
+		metric.WithUnit("{panic}")))
+
+	responseValidationFailures = generics.Must(meter.Int64Counter("http.server.response.validation.failures",
+		metric.WithDescription("Total number of http responses that did not match the API specification."),
+		metric.WithUnit("{response}")))
+
+	concurrencyLimit = generics.Must(meter.Int64Gauge("http.server.concurrency.limit",
+		metric.WithDescription("Current adaptive concurrency limit of the http server."),
+		metric.WithUnit("{request}")))
+
+	requestsShed = generics.Must(meter.Int64Counter("http.server.requests.shed",
+		metric.WithDescription("Total number of http requests rejected by the adaptive concurrency limiter."),
+		metric.WithUnit("{request}")))
+
+	compressionBytesSaved = generics.Must(meter.Int64Counter("http.server.response.compression.saved",
+		metric.WithDescription("Total number of response body bytes saved by compression."),
+		metric.WithUnit("By")))
+
 	productMetricSubmitted = generics.Must(meter.Int64Counter("prodmetric.submitted",
 		metric.WithDescription("Total number of product metrics submitted."),
 		metric.WithUnit(string(metricdata.UnitDimensionless))))
 )
 
-// Middleware records metrics for HTTP handlers.
-func Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		reqStarted.Add(r.Context(), 1, metric.WithAttributes(HTTPServerMetricAttributesFromHTTPRequest(r)...))
-		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
-		t1 := time.Now()
-		defer func() {
-			reqCompleted.Add(context.Background(), 1, metric.WithAttributes(HTTPServerMetricAttributesFromHTTPResponse(r, ww)...))
-			reqDuration.Record(context.Background(), float64(time.Since(t1).Milliseconds()), metric.WithAttributes(HTTPServerMetricAttributesFromHTTPResponse(r, ww)...))
-		}()
-		next.ServeHTTP(ww, r)
-	})
+// Middleware records metrics for HTTP handlers using the supplied semantic
+// convention mode. Unknown modes are treated as stable.
+func Middleware(mode string) func(http.Handler) http.Handler {
+	legacy := mode == SemconvLegacy || mode == SemconvDup
+	stable := mode != SemconvLegacy
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if legacy {
+				reqStarted.Add(r.Context(), 1, metric.WithAttributes(HTTPServerMetricAttributesFromHTTPRequest(r)...))
+			}
+			if stable {
+				// Active requests are exported as a gauge, which cannot carry
+				// exemplars, so the trace context is not passed.
+				active := metric.WithAttributes(StableHTTPServerMetricAttributesFromHTTPRequest(r)...)
+				serverActiveReqs.Add(context.Background(), 1, active)
+				defer serverActiveReqs.Add(context.Background(), -1, active)
+			}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			t1 := time.Now()
+			defer func() {
+				// Durations are recorded with the request context so that
+				// exemplars can reference the trace started by Tracing.
+				elapsed := time.Since(t1)
+				if legacy {
+					reqCompleted.Add(context.Background(), 1, metric.WithAttributes(HTTPServerMetricAttributesFromHTTPResponse(r, ww)...))
+					reqDuration.Record(r.Context(), float64(elapsed.Milliseconds()), metric.WithAttributes(HTTPServerMetricAttributesFromHTTPResponse(r, ww)...))
+				}
+				if stable {
+					serverReqDuration.Record(r.Context(), elapsed.Seconds(), metric.WithAttributes(StableHTTPServerMetricAttributesFromHTTPResponse(r, ww)...))
+				}
+			}()
+			next.ServeHTTP(ww, r)
+		})
+	}
 }
 
 // HTTPServerMetricAttributesFromHTTPRequest constructs default attributes for
 // an HTTP request.
+//
+// Deprecated: use StableHTTPServerMetricAttributesFromHTTPRequest.
 func HTTPServerMetricAttributesFromHTTPRequest(r *http.Request) []attribute.KeyValue {
 	return []attribute.KeyValue{
 		attribute.String("http.method", r.Method),
@@ -66,10 +140,100 @@ func HTTPServerMetricAttributesFromHTTPRequest(r *http.Request) []attribute.KeyV
 
 // HTTPServerMetricAttributesFromHTTPResponse constructs default attributes for
 // an HTTP response.
+//
+// Deprecated: use StableHTTPServerMetricAttributesFromHTTPResponse.
 func HTTPServerMetricAttributesFromHTTPResponse(r *http.Request, w middleware.WrapResponseWriter) []attribute.KeyValue {
 	return append(HTTPServerMetricAttributesFromHTTPRequest(r), attribute.Int("http.status_code", w.Status()))
 }
 
+// StableHTTPServerMetricAttributesFromHTTPRequest constructs attributes for an
+// HTTP request following the stable HTTP semantic conventions.
+func StableHTTPServerMetricAttributesFromHTTPRequest(r *http.Request) []attribute.KeyValue {
+	fwd := forwarded.FromRequest(r)
+	return []attribute.KeyValue{
+		attribute.String("http.request.method", requestMethod(r.Method)),
+		attribute.String("url.scheme", fwd.Scheme),
+		attribute.String("server.address", serverAddress(fwd.Host)),
+	}
+}
+
+// StableHTTPServerMetricAttributesFromHTTPResponse constructs attributes for
+// an HTTP response following the stable HTTP semantic conventions.
+func StableHTTPServerMetricAttributesFromHTTPResponse(r *http.Request, w middleware.WrapResponseWriter) []attribute.KeyValue {
+	return append(StableHTTPServerMetricAttributesFromHTTPRequest(r), attribute.Int("http.response.status_code", w.Status()))
+}
+
+var knownMethods = []string{
+	http.MethodConnect,
+	http.MethodDelete,
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPatch,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodTrace,
+}
+
+// requestMethod bounds the cardinality of the method attribute as required by
+// the semantic conventions.
+func requestMethod(m string) string {
+	if generics.Contains(knownMethods, m) {
+		return m
+	}
+	return "_OTHER"
+}
+
+// serverAddress strips the port, if any, from a host.
+func serverAddress(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// PanicRecovered records a panic recovered while handling an HTTP request.
+func PanicRecovered(ctx context.Context, r *http.Request) {
+	panicsRecovered.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("http.request.method", requestMethod(r.Method)),
+	))
+}
+
+// ResponseValidationFailed records a response that did not match the API
+// specification for the supplied route.
+func ResponseValidationFailed(ctx context.Context, r *http.Request, route string) {
+	responseValidationFailures.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("http.request.method", requestMethod(r.Method)),
+		attribute.String("http.route", route),
+	))
+}
+
+// ConcurrencyLimitChanged records the current adaptive concurrency limit.
+// Gauges cannot carry exemplars, so no request context is used.
+func ConcurrencyLimitChanged(limit int) {
+	concurrencyLimit.Record(context.Background(), int64(limit))
+}
+
+// RequestShed records a request rejected by the adaptive concurrency limiter.
+func RequestShed(ctx context.Context, r *http.Request) {
+	requestsShed.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("http.request.method", requestMethod(r.Method)),
+	))
+}
+
+// CompressionBytesSaved records the bytes saved by compressing a response with
+// the supplied encoding. Responses that grew when compressed are not recorded,
+// as a counter cannot decrease.
+func CompressionBytesSaved(ctx context.Context, r *http.Request, encoding string, saved int64) {
+	if saved <= 0 {
+		return
+	}
+	compressionBytesSaved.Add(ctx, saved, metric.WithAttributes(
+		attribute.String("http.request.method", requestMethod(r.Method)),
+		attribute.String("http.response.content_encoding", encoding),
+	))
+}
+
 // ProductMetricSubmit records an product metric submission.
 func ProductMetricSubmit(ctx context.Context, account, repository string, success bool) {
 	productMetricSubmitted.Add(ctx, 1, metric.WithAttributes([]attribute.KeyValue{
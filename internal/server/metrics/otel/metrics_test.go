@@ -0,0 +1,85 @@
+package otel
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestStableHTTPServerMetricAttributesFromHTTPRequest(t *testing.T) {
+	type arguments struct {
+		method string
+		host   string
+		tls    bool
+	}
+	type want struct {
+		attrs []attribute.KeyValue
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"PlainHTTP": {
+			reason: "A plain HTTP request should use the http scheme and strip the port from the server address.",
+			args: arguments{
+				method: http.MethodGet,
+				host:   "api.upbound.io:8081",
+			},
+			want: want{
+				attrs: []attribute.KeyValue{
+					attribute.String("http.request.method", http.MethodGet),
+					attribute.String("url.scheme", "http"),
+					attribute.String("server.address", "api.upbound.io"),
+				},
+			},
+		},
+		"TLS": {
+			reason: "A TLS request should use the https scheme.",
+			args: arguments{
+				method: http.MethodPost,
+				host:   "api.upbound.io",
+				tls:    true,
+			},
+			want: want{
+				attrs: []attribute.KeyValue{
+					attribute.String("http.request.method", http.MethodPost),
+					attribute.String("url.scheme", "https"),
+					attribute.String("server.address", "api.upbound.io"),
+				},
+			},
+		},
+		"UnknownMethod": {
+			reason: "An unknown method should be reported as _OTHER to bound cardinality.",
+			args: arguments{
+				method: "PURGE",
+				host:   "api.upbound.io",
+			},
+			want: want{
+				attrs: []attribute.KeyValue{
+					attribute.String("http.request.method", "_OTHER"),
+					attribute.String("url.scheme", "http"),
+					attribute.String("server.address", "api.upbound.io"),
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.args.method, "/v1/demo", nil)
+			req.Host = tc.args.host
+			req.TLS = nil
+			if tc.args.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			got := StableHTTPServerMetricAttributesFromHTTPRequest(req)
+			if diff := cmp.Diff(tc.want.attrs, got, cmp.AllowUnexported(attribute.Value{})); diff != "" {
+				t.Errorf("\n%s\nStableHTTPServerMetricAttributesFromHTTPRequest(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
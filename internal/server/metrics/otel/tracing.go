@@ -0,0 +1,23 @@
+package otel
+
+import (
+	"net/http"
+
+	opentel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = opentel.Tracer("build-submodule-demo")
+
+// Tracing starts a server span for each request, continuing any trace context
+// propagated by the caller. Metrics recorded with the request context while
+// the span is sampled carry trace and span ID exemplars.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := opentel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	opentel "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/upbound/build-submodule-demo/internal"
+	"github.com/upbound/build-submodule-demo/internal/server/metrics/otel"
+)
+
+// Metrics export modes.
+const (
+	ModePrometheus = "prometheus"
+	ModeOTLP       = "otlp"
+	ModeBoth       = "both"
+)
+
+const (
+	errPrometheusExporter = "failed to create prometheus exporter"
+	errOTLPExporter       = "failed to create otlp exporter"
+	errUnknownMode        = "unknown metrics mode"
+)
+
+// PrometheusEnabled returns true if metrics should be served for Prometheus to
+// scrape.
+func PrometheusEnabled(opts internal.MetricsOptions) bool {
+	return opts.MetricsMode == ModePrometheus || opts.MetricsMode == ModeBoth
+}
+
+// OTLPEnabled returns true if metrics should be pushed to an OTLP collector.
+func OTLPEnabled(opts internal.MetricsOptions) bool {
+	return opts.MetricsMode == ModeOTLP || opts.MetricsMode == ModeBoth
+}
+
+// NewMeterProvider constructs a meter provider with readers for the configured
+// metrics mode and sets it as the global meter provider to allow access from
+// other packages. Callers are responsible for shutting down the provider so
+// that pending metrics are flushed.
+func NewMeterProvider(ctx context.Context, opts internal.MetricsOptions) (*metric.MeterProvider, error) {
+	if !PrometheusEnabled(opts) && !OTLPEnabled(opts) {
+		return nil, errors.Errorf("%s: %q", errUnknownMode, opts.MetricsMode)
+	}
+
+	mopts := []metric.Option{
+		metric.WithResource(resource.NewSchemaless(attribute.String("service.name", "build-submodule-demo"))),
+		metric.WithView(metric.NewView(
+			metric.Instrument{Name: otel.HTTPServerRequestDuration},
+			metric.Stream{Aggregation: metric.AggregationExplicitBucketHistogram{
+				Boundaries: otel.HTTPServerRequestDurationBuckets,
+			}},
+		)),
+	}
+	if PrometheusEnabled(opts) {
+		exporter, err := prometheus.New()
+		if err != nil {
+			return nil, errors.Wrap(err, errPrometheusExporter)
+		}
+		mopts = append(mopts, metric.WithReader(exporter))
+	}
+	if OTLPEnabled(opts) {
+		eopts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpoint(opts.OTLPEndpoint),
+			otlpmetrichttp.WithHeaders(opts.OTLPHeaders),
+		}
+		if opts.OTLPInsecure {
+			eopts = append(eopts, otlpmetrichttp.WithInsecure())
+		}
+		exporter, err := otlpmetrichttp.New(ctx, eopts...)
+		if err != nil {
+			return nil, errors.Wrap(err, errOTLPExporter)
+		}
+		mopts = append(mopts, metric.WithReader(metric.NewPeriodicReader(exporter, metric.WithInterval(opts.OTLPInterval))))
+	}
+
+	provider := metric.NewMeterProvider(mopts...)
+	opentel.SetMeterProvider(provider)
+	return provider, nil
+}
+
+// NewTracerProvider constructs a tracer provider and sets it, along with the
+// W3C trace context propagator, as the global provider. Requests are only
+// sampled if the caller sampled the propagated trace. Spans are not exported;
+// they link recorded metrics to the caller's trace through exemplars.
+func NewTracerProvider() *trace.TracerProvider {
+	provider := trace.NewTracerProvider(trace.WithSampler(trace.ParentBased(trace.NeverSample())))
+	opentel.SetTracerProvider(provider)
+	opentel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider
+}
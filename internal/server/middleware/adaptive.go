@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	chimid "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/upbound/build-submodule-demo/internal/generics"
+	"github.com/upbound/build-submodule-demo/internal/server/metrics/otel"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errLoadShed = "server is overloaded"
+)
+
+// AdaptiveLimiter is concurrency limiting middleware that adjusts its limit
+// from observed request latency. The limit grows by one for each limit's
+// worth of requests that complete within the target latency while the limit
+// is in use, and shrinks multiplicatively when they do not (AIMD).
+//
+// Prioritized requests, such as health probes and private traffic, are never
+// shed but count towards the requests in flight, so that other traffic is
+// shed first when the server is overloaded.
+type AdaptiveLimiter struct {
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+
+	min, max     int
+	target       time.Duration
+	backoff      float64
+	retryAfter   time.Duration
+	prioritized  []string
+	limitChanged func(limit int)
+}
+
+// AdaptiveLimiterOpt modifies an adaptive limiter.
+type AdaptiveLimiterOpt func(l *AdaptiveLimiter)
+
+// AdaptiveWithLimits sets the initial, minimum and maximum concurrency limits.
+func AdaptiveWithLimits(initial, min, max int) AdaptiveLimiterOpt {
+	return func(l *AdaptiveLimiter) {
+		l.limit = float64(initial)
+		l.min = min
+		l.max = max
+	}
+}
+
+// AdaptiveWithTargetLatency sets the latency above which the limit is
+// decreased.
+func AdaptiveWithTargetLatency(d time.Duration) AdaptiveLimiterOpt {
+	return func(l *AdaptiveLimiter) {
+		l.target = d
+	}
+}
+
+// AdaptiveWithBackoff sets the factor the limit is multiplied by when a
+// request exceeds the target latency.
+func AdaptiveWithBackoff(f float64) AdaptiveLimiterOpt {
+	return func(l *AdaptiveLimiter) {
+		l.backoff = f
+	}
+}
+
+// AdaptiveWithRetryAfter sets the Retry-After duration returned to shed
+// callers.
+func AdaptiveWithRetryAfter(d time.Duration) AdaptiveLimiterOpt {
+	return func(l *AdaptiveLimiter) {
+		l.retryAfter = d
+	}
+}
+
+// AdaptiveWithPrioritizedPaths sets paths that are never shed.
+func AdaptiveWithPrioritizedPaths(paths ...string) AdaptiveLimiterOpt {
+	return func(l *AdaptiveLimiter) {
+		l.prioritized = paths
+	}
+}
+
+// NewAdaptiveLimiter constructs a new adaptive limiter.
+func NewAdaptiveLimiter(opts ...AdaptiveLimiterOpt) *AdaptiveLimiter {
+	l := &AdaptiveLimiter{
+		limit:        100,
+		min:          10,
+		max:          1000,
+		target:       250 * time.Millisecond,
+		backoff:      0.9,
+		retryAfter:   time.Second,
+		limitChanged: otel.ConcurrencyLimitChanged,
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	l.limitChanged(int(l.limit))
+	return l
+}
+
+// Limit sheds requests over the current limit with a service unavailable
+// problem. Requests to prioritized paths are never shed.
+func (l *AdaptiveLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prioritized := generics.Contains(l.prioritized, r.URL.Path)
+		if !l.acquire(prioritized) {
+			otel.RequestShed(r.Context(), r)
+			w.Header().Set("Retry-After", seconds(l.retryAfter))
+			problem.Error(w, r, http.StatusServiceUnavailable, errLoadShed)
+			return
+		}
+		l.serve(w, r, next)
+	})
+}
+
+// Prioritized counts requests towards the requests in flight without ever
+// shedding them. It is intended for servers whose traffic should take
+// precedence, such as the private server.
+func (l *AdaptiveLimiter) Prioritized(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.acquire(true)
+		l.serve(w, r, next)
+	})
+}
+
+func (l *AdaptiveLimiter) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	ww := chimid.NewWrapResponseWriter(w, r.ProtoMajor)
+	start := time.Now()
+	defer func() {
+		// Server errors are treated like slow requests, as both indicate an
+		// unhealthy downstream.
+		l.release(time.Since(start) <= l.target && ww.Status() < http.StatusInternalServerError)
+	}()
+	next.ServeHTTP(ww, r)
+}
+
+// acquire reserves a slot, returning false if the request should be shed.
+func (l *AdaptiveLimiter) acquire(prioritized bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !prioritized && l.inflight >= int(l.limit) {
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+// release frees a slot and adjusts the limit from the outcome of the request.
+func (l *AdaptiveLimiter) release(ok bool) {
+	l.mu.Lock()
+	prev := int(l.limit)
+	inflight := l.inflight
+	l.inflight--
+	switch {
+	case !ok:
+		l.limit = math.Max(float64(l.min), l.limit*l.backoff)
+	case inflight*2 >= int(l.limit):
+		// Only grow the limit while at least half of it is in use, otherwise
+		// it would grow without bound when idle. Each request grows it by a
+		// fraction, so that it grows with round trips rather than with the
+		// request rate.
+		l.limit = math.Min(float64(l.max), l.limit+1/l.limit)
+	}
+	cur := int(l.limit)
+	l.mu.Unlock()
+	if cur != prev {
+		l.limitChanged(cur)
+	}
+}
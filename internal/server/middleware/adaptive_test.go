@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAdaptiveLimiterRelease(t *testing.T) {
+	type arguments struct {
+		inflight int
+		ok       bool
+		// requests is the number of requests released, if more than one.
+		requests int
+	}
+	type want struct {
+		limit int
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Increase": {
+			reason: "The limit should grow by one for each limit's worth of fast requests while the limit is in use.",
+			args: arguments{
+				inflight: 10,
+				ok:       true,
+				requests: 21,
+			},
+			want: want{
+				limit: 21,
+			},
+		},
+		"SustainedLoad": {
+			reason: "The limit should grow with the square root of the number of fast requests, not with the request rate.",
+			args: arguments{
+				inflight: 100,
+				ok:       true,
+				requests: 500,
+			},
+			want: want{
+				limit: 37,
+			},
+		},
+		"Idle": {
+			reason: "The limit should not grow when less than half of it is in use.",
+			args: arguments{
+				inflight: 1,
+				ok:       true,
+			},
+			want: want{
+				limit: 20,
+			},
+		},
+		"Decrease": {
+			reason: "The limit should shrink multiplicatively when a request is slow.",
+			args: arguments{
+				inflight: 10,
+				ok:       false,
+			},
+			want: want{
+				limit: 10,
+			},
+		},
+		"Minimum": {
+			reason: "The limit should not shrink below the minimum.",
+			args: arguments{
+				inflight: 1,
+				ok:       false,
+			},
+			want: want{
+				limit: 10,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			l := NewAdaptiveLimiter(
+				AdaptiveWithLimits(20, 10, 100),
+				AdaptiveWithBackoff(0.5),
+				AdaptiveWithTargetLatency(time.Second),
+			)
+			for i := 0; i < max(tc.args.requests, 1); i++ {
+				l.inflight = tc.args.inflight
+				l.release(tc.args.ok)
+			}
+			if diff := cmp.Diff(tc.want.limit, int(l.limit)); diff != "" {
+				t.Errorf("\n%s\nrelease(...): -want limit, +got limit:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAdaptiveLimiterAcquire(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptiveWithLimits(1, 1, 1))
+	if !l.acquire(false) {
+		t.Errorf("acquire(false): expected request under the limit to be allowed")
+	}
+	if l.acquire(false) {
+		t.Errorf("acquire(false): expected request over the limit to be shed")
+	}
+	if !l.acquire(true) {
+		t.Errorf("acquire(true): expected prioritized request to be allowed")
+	}
+}
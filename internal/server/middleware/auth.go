@@ -2,11 +2,14 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 
 	"github.com/upbound/build-submodule-demo/internal/client/auth"
+	"github.com/upbound/build-submodule-demo/internal/log"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
 )
 
 const (
@@ -49,7 +52,7 @@ func (a *AuthN) Required(next http.Handler) http.Handler {
 		c, err := r.Cookie(auth.SessionCookieName)
 		if err != nil {
 			a.log.Debug(errMissingSession, "error", err)
-			w.WriteHeader(http.StatusUnauthorized)
+			problem.Error(w, r, http.StatusUnauthorized, errMissingSession)
 			return
 		}
 		id, err := a.mgr.GetUserID(r.Context(), c.Value)
@@ -57,9 +60,10 @@ func (a *AuthN) Required(next http.Handler) http.Handler {
 			a.log.Debug(errGetUserID, "error", err)
 			// TODO(hasheddan): consider returning another status code if error
 			// is our fault.
-			w.WriteHeader(http.StatusUnauthorized)
+			problem.Error(w, r, http.StatusUnauthorized, errGetUserID)
 			return
 		}
+		log.SetPrincipal(r, fmt.Sprintf("%s:%d", auth.User, id))
 		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auth.UserKey, id)))
 	})
 }
@@ -78,6 +82,7 @@ func (a *AuthN) Optional(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
+		log.SetPrincipal(r, fmt.Sprintf("%s:%d", auth.User, id))
 		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auth.UserKey, id)))
 	})
 }
@@ -0,0 +1,441 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/upbound/build-submodule-demo/internal/generics"
+	"github.com/upbound/build-submodule-demo/internal/server/metrics/otel"
+)
+
+// Content encodings supported by Compressor.
+const (
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+
+	encodingIdentity = "identity"
+)
+
+// DefaultCompressibleTypes are the media types compressed by default.
+var DefaultCompressibleTypes = []string{
+	"text/*",
+	"application/json",
+	"application/*+json",
+	"application/javascript",
+	"application/xml",
+	"application/yaml",
+	"image/svg+xml",
+}
+
+// streamingTypes are never compressed, as they must be delivered as they are
+// written.
+var streamingTypes = []string{"text/event-stream"}
+
+// An encoder compresses a response body.
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	EncodingZstd: {New: func() any {
+		e, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return e
+	}},
+	EncodingBrotli: {New: func() any {
+		return brotli.NewWriterLevel(nil, 4)
+	}},
+	EncodingGzip: {New: func() any {
+		e, _ := gzip.NewWriterLevel(nil, 5)
+		return e
+	}},
+}
+
+// Compressor is response compression middleware. The encoding is negotiated
+// from the Accept-Encoding q-values, with ties broken by the order of the
+// configured encodings. Responses are only compressed if they are at least
+// the minimum size and of an allowed content type, and are not already
+// encoded or streamed.
+type Compressor struct {
+	encodings []string
+	minSize   int
+	types     []string
+}
+
+// CompressorOpt modifies compression middleware.
+type CompressorOpt func(c *Compressor)
+
+// CompressorWithEncodings sets the supported encodings in order of
+// preference.
+func CompressorWithEncodings(encodings ...string) CompressorOpt {
+	return func(c *Compressor) {
+		c.encodings = encodings
+	}
+}
+
+// CompressorWithMinSize sets the minimum size of compressed responses.
+func CompressorWithMinSize(n int) CompressorOpt {
+	return func(c *Compressor) {
+		c.minSize = n
+	}
+}
+
+// CompressorWithContentTypes sets the media types that are compressed.
+// Patterns may use a wildcard subtype, e.g. text/*, or a wildcard subtype with
+// a suffix, e.g. application/*+json.
+func CompressorWithContentTypes(types ...string) CompressorOpt {
+	return func(c *Compressor) {
+		c.types = types
+	}
+}
+
+// NewCompressor constructs new compression middleware.
+func NewCompressor(opts ...CompressorOpt) *Compressor {
+	c := &Compressor{
+		encodings: []string{EncodingZstd, EncodingBrotli, EncodingGzip},
+		minSize:   1024,
+		types:     DefaultCompressibleTypes,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Handler compresses responses with the negotiated encoding. The ETags of
+// compressed responses are suffixed with their encoding, as they identify a
+// different representation. The suffix of the negotiated encoding is removed
+// from the ETags of conditional requests before they are handled, and is
+// restored on the ETag of a not modified response that matched it. HEAD
+// requests are negotiated like GET requests, so that they return the same
+// headers, but no body is written.
+func (c *Compressor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add(headerVary, "Accept-Encoding")
+		enc := c.negotiate(r.Header.Values("Accept-Encoding"))
+		if enc == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, c: c, r: r, encoding: enc, status: http.StatusOK}
+		for _, k := range []string{"If-Match", "If-None-Match"} {
+			if v := r.Header.Get(k); v != "" {
+				list, unsuffixed := unsuffixETags(v, enc)
+				r.Header.Set(k, list)
+				cw.unsuffixed = append(cw.unsuffixed, unsuffixed...)
+			}
+		}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiate returns the preferred supported encoding acceptable to the
+// client, or an empty string if the response should not be encoded.
+func (c *Compressor) negotiate(values []string) string {
+	q := map[string]float64{}
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			weight := 1.0
+			if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					weight = f
+				}
+			}
+			q[name] = weight
+		}
+	}
+	type candidate struct {
+		encoding string
+		q        float64
+	}
+	candidates := []candidate{}
+	for _, e := range c.encodings {
+		if _, ok := encoderPools[e]; !ok {
+			continue
+		}
+		w, ok := q[e]
+		if !ok {
+			w, ok = q["*"]
+		}
+		if ok && w > 0 {
+			candidates = append(candidates, candidate{encoding: e, q: w})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	// Prefer an uncompressed response if the client explicitly does.
+	if iq, ok := q[encodingIdentity]; ok && iq > candidates[0].q {
+		return ""
+	}
+	return candidates[0].encoding
+}
+
+// unsuffixETags removes the suffix of the supplied encoding from a list of
+// ETags. It returns the list and the ETags that had the suffix removed.
+func unsuffixETags(list, encoding string) (string, []string) {
+	suffix := "-" + encoding + `"`
+	tags := strings.Split(list, ",")
+	unsuffixed := []string{}
+	for i, t := range tags {
+		t = strings.TrimSpace(t)
+		if strings.HasSuffix(t, suffix) {
+			t = strings.TrimSuffix(t, suffix) + `"`
+			unsuffixed = append(unsuffixed, t)
+		}
+		tags[i] = t
+	}
+	return strings.Join(tags, ", "), unsuffixed
+}
+
+// suffixETag suffixes an ETag with an encoding.
+func suffixETag(etag, encoding string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// compressible reports whether a media type may be compressed.
+func (c *Compressor) compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, s := range streamingTypes {
+		if mt == s {
+			return false
+		}
+	}
+	for _, p := range c.types {
+		if matchMediaType(p, mt) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMediaType matches a media type against a pattern such as
+// application/json, text/* or application/*+json.
+func matchMediaType(pattern, mt string) bool {
+	ptype, psub, ok := strings.Cut(strings.ToLower(pattern), "/")
+	if !ok {
+		return false
+	}
+	mtype, msub, _ := strings.Cut(mt, "/")
+	if ptype != mtype {
+		return false
+	}
+	switch {
+	case psub == "*":
+		return true
+	case strings.HasPrefix(psub, "*"):
+		return strings.HasSuffix(msub, psub[1:])
+	}
+	return psub == msub
+}
+
+// compressWriter buffers a response until it is known whether it should be
+// compressed, then writes it either compressed or as is.
+type compressWriter struct {
+	http.ResponseWriter
+	c        *Compressor
+	r        *http.Request
+	encoding string
+
+	// unsuffixed are the ETags of the request that had the encoding suffix
+	// removed.
+	unsuffixed []string
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         bytes.Buffer
+
+	// compressedHead is true if the response to a HEAD request was given the
+	// headers of a compressed response, and its body is discarded.
+	compressedHead bool
+
+	enc encoder
+	in  int
+	out *countingWriter
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	h := w.Header()
+	if etag := h.Get("ETag"); status == http.StatusNotModified && generics.Contains(w.unsuffixed, etag) {
+		// The client holds the validator of the compressed representation,
+		// which must not change on revalidation.
+		h.Set("ETag", suffixETag(etag, w.encoding))
+	}
+	switch {
+	case status < http.StatusOK, status == http.StatusNoContent, status == http.StatusNotModified:
+		w.passthrough()
+	case h.Get("Content-Encoding") != "":
+		w.passthrough()
+	case h.Get("Content-Type") != "" && !w.c.compressible(h.Get("Content-Type")):
+		w.passthrough()
+	}
+	if cl, err := strconv.Atoi(h.Get("Content-Length")); err == nil && cl < w.c.minSize {
+		w.passthrough()
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.decided {
+		switch {
+		case w.enc != nil:
+			w.in += len(p)
+			return w.enc.Write(p)
+		case w.compressedHead:
+			return len(p), nil
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	n, _ := w.buf.Write(p)
+	if w.buf.Len() >= w.c.minSize {
+		if err := w.decide(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Flush writes buffered data. Responses flushed before compression starts
+// are treated as streams and are not compressed.
+func (w *compressWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+	if !w.decided {
+		w.passthrough()
+	}
+	if w.enc != nil {
+		if f, ok := w.enc.(interface{ Flush() error }); ok {
+			_ = f.Flush()
+		}
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// passthrough writes the response as is.
+func (w *compressWriter) passthrough() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// decide compresses the response if it is of a compressible type, sniffing
+// the type from the buffered body if it was not set.
+func (w *compressWriter) decide() error {
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+	if !w.c.compressible(h.Get("Content-Type")) {
+		w.passthrough()
+		return nil
+	}
+	w.decided = true
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", w.encoding)
+	if etag := h.Get("ETag"); etag != "" {
+		h.Set("ETag", suffixETag(etag, w.encoding))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.r.Method == http.MethodHead {
+		w.compressedHead = true
+		w.buf.Reset()
+		return nil
+	}
+
+	e := encoderPools[w.encoding].Get().(encoder)
+	w.out = &countingWriter{w: w.ResponseWriter}
+	e.Reset(w.out)
+	w.enc = e
+	w.in = w.buf.Len()
+	_, err := e.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// close completes the response, writing small responses as is.
+func (w *compressWriter) close() {
+	if !w.wroteHeader && !w.headCompressible() {
+		// Nothing was written, so let the server write the default response.
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if !w.decided {
+		if w.headCompressible() {
+			_ = w.decide()
+			return
+		}
+		w.passthrough()
+		return
+	}
+	if w.enc == nil {
+		return
+	}
+	_ = w.enc.Close()
+	w.enc.Reset(nil)
+	encoderPools[w.encoding].Put(w.enc)
+	otel.CompressionBytesSaved(w.r.Context(), w.r, w.encoding, int64(w.in-w.out.n))
+}
+
+// headCompressible reports whether the response to a HEAD request that wrote
+// no body describes a GET response that would be compressed.
+func (w *compressWriter) headCompressible() bool {
+	if w.r.Method != http.MethodHead || w.decided || w.status != http.StatusOK || w.Header().Get("Content-Type") == "" {
+		return false
+	}
+	cl, err := strconv.Atoi(w.Header().Get("Content-Length"))
+	return err == nil && cl >= w.c.minSize
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/google/go-cmp/cmp"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressorNegotiate(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		accept string
+		want   string
+	}{
+		"None": {
+			reason: "Responses should not be encoded if the client accepts no encodings.",
+			want:   "",
+		},
+		"Preference": {
+			reason: "Ties should be broken by the configured order.",
+			accept: "gzip, br, zstd",
+			want:   EncodingZstd,
+		},
+		"QValues": {
+			reason: "The encoding with the highest q-value should be chosen.",
+			accept: "zstd;q=0.5, br;q=0.8, gzip",
+			want:   EncodingGzip,
+		},
+		"Refused": {
+			reason: "Encodings with a q-value of zero should not be chosen.",
+			accept: "*, zstd;q=0",
+			want:   EncodingBrotli,
+		},
+		"Identity": {
+			reason: "Responses should not be encoded if the client prefers identity.",
+			accept: "identity, gzip;q=0.5",
+			want:   "",
+		},
+	}
+	c := NewCompressor()
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var values []string
+			if tc.accept != "" {
+				values = []string{tc.accept}
+			}
+			if diff := cmp.Diff(tc.want, c.negotiate(values)); diff != "" {
+				t.Errorf("\n%s\nnegotiate(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCompressorHandler(t *testing.T) {
+	large := strings.Repeat(`{"name":"demo"}`, 200)
+	type arguments struct {
+		accept      string
+		contentType string
+		encoding    string
+		body        string
+		flush       bool
+	}
+	type want struct {
+		encoding string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Zstd": {
+			reason: "Large JSON responses should be compressed with zstd.",
+			args:   arguments{accept: "zstd", contentType: "application/json", body: large},
+			want:   want{encoding: EncodingZstd},
+		},
+		"Brotli": {
+			reason: "Large JSON responses should be compressed with brotli.",
+			args:   arguments{accept: "br", contentType: "application/problem+json", body: large},
+			want:   want{encoding: EncodingBrotli},
+		},
+		"Gzip": {
+			reason: "Large responses of a sniffed text type should be compressed with gzip.",
+			args:   arguments{accept: "gzip", body: large},
+			want:   want{encoding: EncodingGzip},
+		},
+		"Small": {
+			reason: "Responses under the minimum size should not be compressed.",
+			args:   arguments{accept: "gzip", contentType: "application/json", body: `{}`},
+		},
+		"ContentType": {
+			reason: "Responses of types not in the allowlist should not be compressed.",
+			args:   arguments{accept: "gzip", contentType: "application/octet-stream", body: large},
+		},
+		"Encoded": {
+			reason: "Responses that are already encoded should not be compressed again.",
+			args:   arguments{accept: "gzip", contentType: "application/json", encoding: "deflate", body: large},
+			want:   want{encoding: "deflate"},
+		},
+		"Streaming": {
+			reason: "Responses flushed before compression starts should not be compressed.",
+			args:   arguments{accept: "gzip", contentType: "application/json", body: large, flush: true},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := NewCompressor().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.args.contentType != "" {
+					w.Header().Set("Content-Type", tc.args.contentType)
+				}
+				if tc.args.encoding != "" {
+					w.Header().Set("Content-Encoding", tc.args.encoding)
+				}
+				if tc.args.flush {
+					_, _ = w.Write([]byte(tc.args.body[:10]))
+					w.(http.Flusher).Flush()
+					_, _ = w.Write([]byte(tc.args.body[10:]))
+					return
+				}
+				_, _ = w.Write([]byte(tc.args.body))
+			}))
+			req, _ := http.NewRequestWithContext(context.Background(), "GET", "/v1/demo", nil)
+			req.Header.Set("Accept-Encoding", tc.args.accept)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			if diff := cmp.Diff(tc.want.encoding, rr.Header().Get("Content-Encoding")); diff != "" {
+				t.Fatalf("\n%s\nHandler(...): -want encoding, +got encoding:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.args.body, decode(t, tc.want.encoding, rr.Body.Bytes())); diff != "" {
+				t.Errorf("\n%s\nHandler(...): -want body, +got body:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func decode(t *testing.T, encoding string, b []byte) string {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(b)
+	switch encoding {
+	case EncodingZstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+		r = d
+	case EncodingBrotli:
+		r = brotli.NewReader(r)
+	case EncodingGzip:
+		g, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = g
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestCompressorETag(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+	type arguments struct {
+		method      string
+		encoding    string
+		ifNoneMatch string
+		// omitBody is true if the handler omits the body of HEAD responses,
+		// setting only their content length.
+		omitBody bool
+	}
+	type want struct {
+		status   int
+		etag     string
+		encoding string
+		body     bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Compressed": {
+			reason: "The ETags of compressed responses should be suffixed with their encoding.",
+			args:   arguments{encoding: EncodingGzip},
+			want:   want{status: http.StatusOK, etag: `"v1-gzip"`, encoding: EncodingGzip, body: true},
+		},
+		"NotModified": {
+			reason: "The encoding suffix should be removed from conditional requests, and kept on the ETag of the not modified response.",
+			args:   arguments{encoding: EncodingGzip, ifNoneMatch: `"v1-gzip"`},
+			want:   want{status: http.StatusNotModified, etag: `"v1-gzip"`},
+		},
+		"OtherEncoding": {
+			reason: "The suffix of an encoding other than the negotiated one should not match.",
+			args:   arguments{encoding: EncodingBrotli, ifNoneMatch: `"v1-gzip"`},
+			want:   want{status: http.StatusOK, etag: `"v1-br"`, encoding: EncodingBrotli, body: true},
+		},
+		"Identity": {
+			reason: "The ETag of a compressed representation should not match an uncompressed one.",
+			args:   arguments{ifNoneMatch: `"v1-gzip"`},
+			want:   want{status: http.StatusOK, etag: `"v1"`, body: true},
+		},
+		"IdentityNotModified": {
+			reason: "The ETags of uncompressed representations should be left as is.",
+			args:   arguments{ifNoneMatch: `"v1"`},
+			want:   want{status: http.StatusNotModified, etag: `"v1"`},
+		},
+		"Head": {
+			reason: "HEAD requests should be negotiated like GET requests, without writing a body.",
+			args:   arguments{method: http.MethodHead, encoding: EncodingGzip},
+			want:   want{status: http.StatusOK, etag: `"v1-gzip"`, encoding: EncodingGzip},
+		},
+		"HeadNotModified": {
+			reason: "The validator of a HEAD request should match the compressed representation.",
+			args:   arguments{method: http.MethodHead, encoding: EncodingGzip, ifNoneMatch: `"v1-gzip"`},
+			want:   want{status: http.StatusNotModified, etag: `"v1-gzip"`},
+		},
+		"HeadWithoutBody": {
+			reason: "A HEAD response should be compressed if its content length would be, even if the handler writes no body.",
+			args:   arguments{method: http.MethodHead, encoding: EncodingGzip, omitBody: true},
+			want:   want{status: http.StatusOK, etag: `"v1-gzip"`, encoding: EncodingGzip},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := NewCompressor().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				if r.Header.Get("If-None-Match") == `"v1"` {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("Content-Type", "text/plain")
+				if tc.args.omitBody {
+					w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+					return
+				}
+				_, _ = w.Write([]byte(body))
+			}))
+			method := http.MethodGet
+			if tc.args.method != "" {
+				method = tc.args.method
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			if tc.args.encoding != "" {
+				req.Header.Set("Accept-Encoding", tc.args.encoding)
+			}
+			if tc.args.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.args.ifNoneMatch)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			got := want{status: rr.Code, etag: rr.Header().Get("ETag"), encoding: rr.Header().Get("Content-Encoding"), body: rr.Body.Len() > 0}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nHandler(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
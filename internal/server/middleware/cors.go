@@ -0,0 +1,310 @@
+package middleware
+
+import (
+	"bufio"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errOriginNotAllowed = "origin is not allowed"
+	errMethodNotAllowed = "method is not allowed"
+	errHeaderNotAllowed = "header is not allowed"
+	errReadOrigins      = "failed to read origins file"
+	errWildcardCreds    = "the * origin cannot be allowed with credentials"
+)
+
+// CORS headers.
+const (
+	headerOrigin           = "Origin"
+	headerRequestMethod    = "Access-Control-Request-Method"
+	headerRequestHeaders   = "Access-Control-Request-Headers"
+	headerAllowOrigin      = "Access-Control-Allow-Origin"
+	headerAllowMethods     = "Access-Control-Allow-Methods"
+	headerAllowHeaders     = "Access-Control-Allow-Headers"
+	headerAllowCredentials = "Access-Control-Allow-Credentials"
+	headerExposeHeaders    = "Access-Control-Expose-Headers"
+	headerMaxAge           = "Access-Control-Max-Age"
+	headerVary             = "Vary"
+)
+
+const (
+	wildcard          = "*"
+	wildcardSubdomain = "://*."
+)
+
+// CORS is Cross-Origin Resource Sharing middleware. Preflight requests are
+// answered directly, so it must be used ahead of request validation and
+// authentication. Allowed origins may be replaced at runtime.
+type CORS struct {
+	log         logging.Logger
+	mu          sync.RWMutex
+	origins     []string
+	methods     []string
+	headers     []string
+	exposed     []string
+	credentials bool
+	maxAge      time.Duration
+}
+
+// CORSOpt modifies CORS middleware.
+type CORSOpt func(c *CORS)
+
+// CORSWithLogger sets the logger for CORS middleware.
+func CORSWithLogger(l logging.Logger) CORSOpt {
+	return func(c *CORS) {
+		c.log = l
+	}
+}
+
+// CORSWithOrigins sets the allowed origins. Origins are matched exactly,
+// except for * which allows any origin and a leading *. in the host, e.g.
+// https://*.example.com, which allows any subdomain. As browsers forbid it, *
+// may not be combined with credentials.
+func CORSWithOrigins(origins ...string) CORSOpt {
+	return func(c *CORS) {
+		c.origins = origins
+	}
+}
+
+// CORSWithMethods sets the allowed methods.
+func CORSWithMethods(methods ...string) CORSOpt {
+	return func(c *CORS) {
+		c.methods = methods
+	}
+}
+
+// CORSWithHeaders sets the allowed request headers. * allows any header.
+func CORSWithHeaders(headers ...string) CORSOpt {
+	return func(c *CORS) {
+		c.headers = headers
+	}
+}
+
+// CORSWithExposedHeaders sets the response headers exposed to callers.
+func CORSWithExposedHeaders(headers ...string) CORSOpt {
+	return func(c *CORS) {
+		c.exposed = headers
+	}
+}
+
+// CORSWithCredentials allows requests with credentials, such as cookies.
+func CORSWithCredentials(allow bool) CORSOpt {
+	return func(c *CORS) {
+		c.credentials = allow
+	}
+}
+
+// CORSWithMaxAge sets how long preflight responses may be cached.
+func CORSWithMaxAge(d time.Duration) CORSOpt {
+	return func(c *CORS) {
+		c.maxAge = d
+	}
+}
+
+// NewCORS constructs new CORS middleware. It returns an error if the *
+// origin is allowed with credentials.
+func NewCORS(opts ...CORSOpt) (*CORS, error) {
+	c := &CORS{
+		log:     logging.NewNopLogger(),
+		methods: []string{http.MethodGet, http.MethodHead, http.MethodPost},
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if err := c.validate(c.origins); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetOrigins replaces the allowed origins. The origins are left unchanged if
+// the * origin is allowed with credentials.
+func (c *CORS) SetOrigins(origins ...string) error {
+	if err := c.validate(origins); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.origins = origins
+	return nil
+}
+
+// validate returns an error if the origins would allow any site to make
+// credentialed requests.
+func (c *CORS) validate(origins []string) error {
+	if c.credentials && containsFold(origins, wildcard) {
+		return errors.New(errWildcardCreds)
+	}
+	return nil
+}
+
+// LoadOrigins replaces the allowed origins with those in the supplied file,
+// one per line. Blank lines and lines starting with # are ignored.
+func (c *CORS) LoadOrigins(path string) error {
+	f, err := os.Open(path) //nolint:gosec // path is supplied by configuration.
+	if err != nil {
+		return errors.Wrap(err, errReadOrigins)
+	}
+	defer f.Close() //nolint:errcheck
+	origins := []string{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		o := strings.TrimSpace(s.Text())
+		if o == "" || strings.HasPrefix(o, "#") {
+			continue
+		}
+		origins = append(origins, o)
+	}
+	if err := s.Err(); err != nil {
+		return errors.Wrap(err, errReadOrigins)
+	}
+	if err := c.SetOrigins(origins...); err != nil {
+		return errors.Wrap(err, errReadOrigins)
+	}
+	c.log.Debug("Loaded CORS origins.", "origins", origins)
+	return nil
+}
+
+// TrustsOrigin reports whether the origin is allowed by name or by subdomain.
+// Origins allowed only by * are not trusted, since * admits any site.
+func (c *CORS) TrustsOrigin(origin string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, o := range c.origins {
+		if strings.EqualFold(o, origin) || matchSubdomain(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler adds CORS headers to requests from allowed origins and answers
+// preflight requests.
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Responses depend on the Origin even if the request has none, as
+		// they then omit CORS headers, so caches must key them by it.
+		w.Header().Add(headerVary, headerOrigin)
+		origin := r.Header.Get(headerOrigin)
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Method == http.MethodOptions && r.Header.Get(headerRequestMethod) != "" {
+			c.preflight(w, r, origin)
+			return
+		}
+		if allowed, ok := c.allowOrigin(origin); ok {
+			w.Header().Set(headerAllowOrigin, allowed)
+			if c.credentials {
+				w.Header().Set(headerAllowCredentials, "true")
+			}
+			if len(c.exposed) > 0 {
+				w.Header().Set(headerExposeHeaders, strings.Join(c.exposed, ", "))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	h := w.Header()
+	h.Add(headerVary, headerRequestMethod)
+	h.Add(headerVary, headerRequestHeaders)
+
+	allowed, ok := c.allowOrigin(origin)
+	if !ok {
+		problem.Error(w, r, http.StatusForbidden, errOriginNotAllowed)
+		return
+	}
+	method := r.Header.Get(headerRequestMethod)
+	if !containsFold(c.methods, method) {
+		problem.Error(w, r, http.StatusForbidden, errMethodNotAllowed)
+		return
+	}
+	headers := requestedHeaders(r)
+	if !containsFold(c.headers, wildcard) {
+		for _, hdr := range headers {
+			if !containsFold(c.headers, hdr) {
+				problem.Error(w, r, http.StatusForbidden, errHeaderNotAllowed)
+				return
+			}
+		}
+	}
+
+	h.Set(headerAllowOrigin, allowed)
+	h.Set(headerAllowMethods, strings.Join(c.methods, ", "))
+	if len(headers) > 0 {
+		h.Set(headerAllowHeaders, strings.Join(headers, ", "))
+	}
+	if c.credentials {
+		h.Set(headerAllowCredentials, "true")
+	}
+	if c.maxAge > 0 {
+		h.Set(headerMaxAge, strconv.Itoa(int(c.maxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowOrigin returns the value of the Access-Control-Allow-Origin header for
+// the origin and whether it is allowed.
+func (c *CORS) allowOrigin(origin string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, o := range c.origins {
+		switch {
+		case o == wildcard && !c.credentials:
+			return wildcard, true
+		case strings.EqualFold(o, origin), matchSubdomain(o, origin):
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// matchSubdomain reports whether the origin is a subdomain of a pattern such
+// as https://*.example.com.
+func matchSubdomain(pattern, origin string) bool {
+	i := strings.Index(pattern, wildcardSubdomain)
+	if i < 0 {
+		return false
+	}
+	scheme, suffix := strings.ToLower(pattern[:i+len("://")]), strings.ToLower(pattern[i+len("://*"):])
+	origin = strings.ToLower(origin)
+	if !strings.HasPrefix(origin, scheme) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	return len(origin) > len(scheme)+len(suffix)
+}
+
+// requestedHeaders returns the headers requested by a preflight request.
+func requestedHeaders(r *http.Request) []string {
+	headers := []string{}
+	for _, v := range r.Header.Values(headerRequestHeaders) {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				headers = append(headers, h)
+			}
+		}
+	}
+	return headers
+}
+
+func containsFold(in []string, s string) bool {
+	for _, v := range in {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCORSHandler(t *testing.T) {
+	type arguments struct {
+		method  string
+		origin  string
+		request string
+		headers string
+	}
+	type want struct {
+		status       int
+		allowOrigin  string
+		allowHeaders string
+		maxAge       string
+		vary         []string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Preflight": {
+			reason: "A preflight request from an allowed origin should be answered directly.",
+			args: arguments{
+				method:  http.MethodOptions,
+				origin:  "https://console.example.com",
+				request: http.MethodPost,
+				headers: "content-type",
+			},
+			want: want{
+				status:       http.StatusNoContent,
+				allowOrigin:  "https://console.example.com",
+				allowHeaders: "content-type",
+				maxAge:       "600",
+				vary:         []string{headerOrigin, headerRequestMethod, headerRequestHeaders},
+			},
+		},
+		"PreflightSubdomain": {
+			reason: "A preflight request from a subdomain of a wildcard origin should be allowed.",
+			args: arguments{
+				method:  http.MethodOptions,
+				origin:  "https://pr-1.preview.example.io",
+				request: http.MethodGet,
+			},
+			want: want{
+				status:      http.StatusNoContent,
+				allowOrigin: "https://pr-1.preview.example.io",
+				maxAge:      "600",
+				vary:        []string{headerOrigin, headerRequestMethod, headerRequestHeaders},
+			},
+		},
+		"PreflightOriginNotAllowed": {
+			reason: "A preflight request from an unknown origin should be rejected.",
+			args: arguments{
+				method:  http.MethodOptions,
+				origin:  "https://example.io",
+				request: http.MethodGet,
+			},
+			want: want{
+				status: http.StatusForbidden,
+				vary:   []string{headerOrigin, headerRequestMethod, headerRequestHeaders},
+			},
+		},
+		"PreflightHeaderNotAllowed": {
+			reason: "A preflight request for a header that is not allowed should be rejected.",
+			args: arguments{
+				method:  http.MethodOptions,
+				origin:  "https://console.example.com",
+				request: http.MethodGet,
+				headers: "X-Secret",
+			},
+			want: want{
+				status: http.StatusForbidden,
+				vary:   []string{headerOrigin, headerRequestMethod, headerRequestHeaders},
+			},
+		},
+		"Simple": {
+			reason: "A request from an allowed origin should be passed on with CORS headers.",
+			args: arguments{
+				method: http.MethodGet,
+				origin: "https://console.example.com",
+			},
+			want: want{
+				status:      http.StatusOK,
+				allowOrigin: "https://console.example.com",
+				vary:        []string{headerOrigin},
+			},
+		},
+		"NoOrigin": {
+			reason: "A request without an origin should be passed on without CORS headers, but vary by origin.",
+			args: arguments{
+				method: http.MethodGet,
+			},
+			want: want{
+				status: http.StatusOK,
+				vary:   []string{headerOrigin},
+			},
+		},
+		"SimpleOriginNotAllowed": {
+			reason: "A request from an unknown origin should be passed on without CORS headers.",
+			args: arguments{
+				method: http.MethodGet,
+				origin: "https://evil.example.org",
+			},
+			want: want{
+				status: http.StatusOK,
+				vary:   []string{headerOrigin},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, err := NewCORS(
+				CORSWithOrigins("https://console.example.com", "https://*.preview.example.io"),
+				CORSWithMethods(http.MethodGet, http.MethodPost),
+				CORSWithHeaders("Content-Type"),
+				CORSWithCredentials(true),
+				CORSWithMaxAge(10*time.Minute),
+			)
+			if err != nil {
+				t.Fatalf("NewCORS(...): %v", err)
+			}
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(context.Background(), tc.args.method, "/v1/demo", nil)
+			req.Header.Set(headerOrigin, tc.args.origin)
+			if tc.args.request != "" {
+				req.Header.Set(headerRequestMethod, tc.args.request)
+			}
+			if tc.args.headers != "" {
+				req.Header.Set(headerRequestHeaders, tc.args.headers)
+			}
+			c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+			got := want{
+				status:       rr.Code,
+				allowOrigin:  rr.Header().Get(headerAllowOrigin),
+				allowHeaders: rr.Header().Get(headerAllowHeaders),
+				maxAge:       rr.Header().Get(headerMaxAge),
+				vary:         rr.Header().Values(headerVary),
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nHandler(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	type want struct {
+		err         bool
+		allowOrigin string
+		loadErr     bool
+	}
+	cases := map[string]struct {
+		reason      string
+		credentials bool
+		want        want
+	}{
+		"Credentials": {
+			reason:      "The * origin should be rejected when credentials are allowed, both at construction and when loaded from a file.",
+			credentials: true,
+			want:        want{err: true, loadErr: true},
+		},
+		"NoCredentials": {
+			reason: "The * origin should allow any origin without reflecting it when credentials are not allowed.",
+			want:   want{allowOrigin: wildcard},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "origins")
+	if err := os.WriteFile(path, []byte("https://console.example.com\n*\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			c, err := NewCORS(CORSWithOrigins(wildcard), CORSWithCredentials(tc.credentials))
+			got.err = err != nil
+			if c == nil {
+				c, _ = NewCORS(CORSWithCredentials(tc.credentials))
+			}
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/v1/demo", nil)
+			req.Header.Set(headerOrigin, "https://evil.example.org")
+			c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+			got.allowOrigin = rr.Header().Get(headerAllowOrigin)
+			got.loadErr = c.LoadOrigins(path) != nil
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nNewCORS(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
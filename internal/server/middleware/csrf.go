@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/upbound/build-submodule-demo/internal/client/auth"
+	"github.com/upbound/build-submodule-demo/internal/protocol"
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errCSRFOrigin = "cross-site request from an untrusted origin"
+	errCSRFToken  = "missing or invalid CSRF token"
+)
+
+const headerSecFetchSite = "Sec-Fetch-Site"
+
+// An OriginPolicy decides whether an origin is trusted. CORS middleware is an
+// OriginPolicy.
+type OriginPolicy interface {
+	TrustsOrigin(origin string) bool
+}
+
+// CSRF protects cookie-authenticated, state-changing requests from
+// cross-site request forgery. Requests are verified in order by their
+// Sec-Fetch-Site header, their Origin header, and finally a double-submit
+// token. Requests without a session cookie are not checked, whether or not
+// they carry a bearer token.
+type CSRF struct {
+	log     logging.Logger
+	trusted OriginPolicy
+}
+
+// CSRFOpt modifies CSRF middleware.
+type CSRFOpt func(c *CSRF)
+
+// CSRFWithLogger sets the logger for CSRF middleware.
+func CSRFWithLogger(l logging.Logger) CSRFOpt {
+	return func(c *CSRF) {
+		c.log = l
+	}
+}
+
+// CSRFWithOriginPolicy sets the policy deciding which cross-site origins are
+// trusted. Only same-origin requests are trusted by default.
+func CSRFWithOriginPolicy(p OriginPolicy) CSRFOpt {
+	return func(c *CSRF) {
+		c.trusted = p
+	}
+}
+
+// NewCSRF constructs new CSRF middleware.
+func NewCSRF(opts ...CSRFOpt) *CSRF {
+	c := &CSRF{
+		log: logging.NewNopLogger(),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Protect rejects cookie-authenticated, state-changing requests that cannot
+// be verified as same-origin or from a trusted origin with a forbidden
+// problem. Safe requests are issued a double-submit token if they have none.
+func (c *CSRF) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasSession(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if safeMethod(r.Method) {
+			c.issueToken(w, r)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if detail, ok := c.verify(r); !ok {
+			c.log.Debug(detail, "method", r.Method, "origin", r.Header.Get(headerOrigin), "site", r.Header.Get(headerSecFetchSite))
+			problem.Error(w, r, http.StatusForbidden, detail)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// verify returns whether a state-changing request may proceed and, if not,
+// why.
+func (c *CSRF) verify(r *http.Request) (string, bool) {
+	origin := r.Header.Get(headerOrigin)
+	switch r.Header.Get(headerSecFetchSite) {
+	case "same-origin", "none":
+		return "", true
+	case "same-site", "cross-site":
+		return errCSRFOrigin, c.trustedOrigin(r, origin)
+	}
+	if origin != "" && origin != "null" {
+		return errCSRFOrigin, c.trustedOrigin(r, origin)
+	}
+	// Neither header is sent by older browsers, so fall back to the
+	// double-submit token.
+	ck, err := r.Cookie(protocol.CSRFCookieName)
+	if err != nil || ck.Value == "" {
+		return errCSRFToken, false
+	}
+	tok := r.Header.Get(protocol.CSRFHeaderName)
+	return errCSRFToken, subtle.ConstantTimeCompare([]byte(ck.Value), []byte(tok)) == 1
+}
+
+// trustedOrigin reports whether the origin is the client-facing origin of the
+// request or is trusted by the origin policy.
+func (c *CSRF) trustedOrigin(r *http.Request, origin string) bool {
+	fwd := forwarded.FromRequest(r)
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Scheme, fwd.Scheme) && strings.EqualFold(u.Host, fwd.Host) {
+		return true
+	}
+	return c.trusted != nil && c.trusted.TrustsOrigin(origin)
+}
+
+// issueToken sets a double-submit token cookie if the request has none.
+func (c *CSRF) issueToken(w http.ResponseWriter, r *http.Request) {
+	if ck, err := r.Cookie(protocol.CSRFCookieName); err == nil && ck.Value != "" {
+		return
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		c.log.Info("Failed to generate CSRF token.", "error", err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     protocol.CSRFCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(b),
+		Path:     "/",
+		Secure:   forwarded.FromRequest(r).Scheme == "https",
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// hasSession reports whether a request carries a session cookie. Such
+// requests are checked even if they also carry a bearer token, as the cookie
+// may be what authenticates them.
+func hasSession(r *http.Request) bool {
+	_, err := r.Cookie(auth.SessionCookieName)
+	return err == nil
+}
+
+func safeMethod(m string) bool {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
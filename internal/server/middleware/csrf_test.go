@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/build-submodule-demo/internal/client/auth"
+	"github.com/upbound/build-submodule-demo/internal/protocol"
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+)
+
+func TestCSRFProtect(t *testing.T) {
+	type arguments struct {
+		method  string
+		headers map[string]string
+		cookies map[string]string
+		origins []string
+	}
+	type want struct {
+		status int
+	}
+	session := map[string]string{auth.SessionCookieName: "session"}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Safe": {
+			reason: "Safe requests should not be checked.",
+			args: arguments{
+				method:  http.MethodGet,
+				headers: map[string]string{headerSecFetchSite: "cross-site"},
+				cookies: session,
+			},
+			want: want{status: http.StatusOK},
+		},
+		"NoSession": {
+			reason: "Requests without a session cookie should not be checked.",
+			args: arguments{
+				method:  http.MethodPost,
+				headers: map[string]string{headerSecFetchSite: "cross-site"},
+			},
+			want: want{status: http.StatusOK},
+		},
+		"Bearer": {
+			reason: "Requests with a bearer token and without a session cookie should not be checked.",
+			args: arguments{
+				method:  http.MethodPost,
+				headers: map[string]string{headerSecFetchSite: "cross-site", headerAuthorization: "Bearer token"},
+			},
+			want: want{status: http.StatusOK},
+		},
+		"BearerWithSession": {
+			reason: "Requests with a session cookie should be checked even if they carry a bearer token.",
+			args: arguments{
+				method:  http.MethodPost,
+				headers: map[string]string{headerSecFetchSite: "cross-site", headerAuthorization: "Bearer bogus"},
+				cookies: session,
+			},
+			want: want{status: http.StatusForbidden},
+		},
+		"SameOrigin": {
+			reason: "Same-origin requests should be allowed.",
+			args: arguments{
+				method:  http.MethodPost,
+				headers: map[string]string{headerSecFetchSite: "same-origin"},
+				cookies: session,
+			},
+			want: want{status: http.StatusOK},
+		},
+		"CrossSiteTrusted": {
+			reason: "Cross-site requests from a trusted origin should be allowed.",
+			args: arguments{
+				method:  http.MethodDelete,
+				headers: map[string]string{headerSecFetchSite: "cross-site", headerOrigin: "https://console.example.com"},
+				cookies: session,
+			},
+			want: want{status: http.StatusOK},
+		},
+		"CrossSiteUntrusted": {
+			reason: "Cross-site requests from an untrusted origin should be rejected.",
+			args: arguments{
+				method:  http.MethodPost,
+				headers: map[string]string{headerSecFetchSite: "cross-site", headerOrigin: "https://evil.example.org"},
+				cookies: session,
+			},
+			want: want{status: http.StatusForbidden},
+		},
+		"CrossSiteWildcard": {
+			reason: "Cross-site requests from an origin allowed only by the * CORS origin should be rejected.",
+			args: arguments{
+				method:  http.MethodPost,
+				headers: map[string]string{headerSecFetchSite: "cross-site", headerOrigin: "https://evil.example.org"},
+				cookies: session,
+				origins: []string{wildcard},
+			},
+			want: want{status: http.StatusForbidden},
+		},
+		"OriginUntrusted": {
+			reason: "Requests from an untrusted origin should be rejected when Sec-Fetch-Site is not sent.",
+			args: arguments{
+				method:  http.MethodPut,
+				headers: map[string]string{headerOrigin: "https://evil.example.org"},
+				cookies: session,
+			},
+			want: want{status: http.StatusForbidden},
+		},
+		"Token": {
+			reason: "Requests with a matching double-submit token should be allowed.",
+			args: arguments{
+				method:  http.MethodPost,
+				headers: map[string]string{protocol.CSRFHeaderName: "token"},
+				cookies: map[string]string{auth.SessionCookieName: "session", protocol.CSRFCookieName: "token"},
+			},
+			want: want{status: http.StatusOK},
+		},
+		"TokenMismatch": {
+			reason: "Requests with a mismatched double-submit token should be rejected.",
+			args: arguments{
+				method:  http.MethodPost,
+				headers: map[string]string{protocol.CSRFHeaderName: "other"},
+				cookies: map[string]string{auth.SessionCookieName: "session", protocol.CSRFCookieName: "token"},
+			},
+			want: want{status: http.StatusForbidden},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			origins := tc.args.origins
+			if origins == nil {
+				origins = []string{"https://console.example.com"}
+			}
+			cors, err := NewCORS(CORSWithOrigins(origins...))
+			if err != nil {
+				t.Fatalf("NewCORS(...): %v", err)
+			}
+			c := NewCSRF(CSRFWithOriginPolicy(cors))
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(context.Background(), tc.args.method, "http://api.example.com/v1/demo", nil)
+			for k, v := range tc.args.headers {
+				req.Header.Set(k, v)
+			}
+			for k, v := range tc.args.cookies {
+				req.AddCookie(&http.Cookie{Name: k, Value: v})
+			}
+			c.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+			if diff := cmp.Diff(tc.want.status, rr.Code); diff != "" {
+				t.Errorf("\n%s\nProtect(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCSRFIssueToken(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		fwd    forwarded.Info
+		want   bool
+	}{
+		"HTTPS": {
+			reason: "The token cookie should be secure if the client-facing scheme is https, e.g. behind a TLS terminating proxy.",
+			fwd:    forwarded.Info{Scheme: "https", Host: "api.example.com"},
+			want:   true,
+		},
+		"HTTP": {
+			reason: "The token cookie should not be secure if the client-facing scheme is http.",
+			fwd:    forwarded.Info{Scheme: "http", Host: "api.example.com"},
+			want:   false,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "http://api.example.com/v1/demo", nil)
+			req = req.WithContext(forwarded.NewContext(req.Context(), tc.fwd))
+			req.AddCookie(&http.Cookie{Name: auth.SessionCookieName, Value: "session"})
+			NewCSRF().Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+			got := false
+			for _, ck := range rr.Result().Cookies() {
+				if ck.Name == protocol.CSRFCookieName {
+					got = ck.Secure
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nProtect(...): -want secure, +got secure:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal/generics"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errUnsupportedEncoding = "unsupported request content encoding %q"
+	errDecompressBody      = "failed to decompress request body"
+	errBodyTooLarge        = "decompressed request body exceeds %d bytes"
+	errBodyRatio           = "request body compression ratio exceeds %g"
+)
+
+// ratioMinSize is the decompressed size below which the compression ratio is
+// not checked, so that small, highly compressible bodies are accepted.
+const ratioMinSize = 64 << 10
+
+// Decompressor is middleware that decompresses request bodies encoded with
+// gzip, zstd or brotli. Bodies are decompressed before they reach request
+// validation, with limits on the decompressed size and compression ratio to
+// protect against decompression bombs.
+type Decompressor struct {
+	maxSize  int64
+	maxRatio float64
+}
+
+// DecompressorOpt modifies decompression middleware.
+type DecompressorOpt func(d *Decompressor)
+
+// DecompressorWithMaxSize sets the maximum decompressed size of a body.
+func DecompressorWithMaxSize(n int64) DecompressorOpt {
+	return func(d *Decompressor) {
+		d.maxSize = n
+	}
+}
+
+// DecompressorWithMaxRatio sets the maximum ratio of decompressed to
+// compressed size of a body.
+func DecompressorWithMaxRatio(r float64) DecompressorOpt {
+	return func(d *Decompressor) {
+		d.maxRatio = r
+	}
+}
+
+// NewDecompressor constructs new decompression middleware.
+func NewDecompressor(opts ...DecompressorOpt) *Decompressor {
+	d := &Decompressor{
+		maxSize:  32 << 20,
+		maxRatio: 100,
+	}
+	for _, o := range opts {
+		o(d)
+	}
+	return d
+}
+
+// Handler replaces compressed request bodies with their decompressed
+// content. Decompressed bodies are limited to the smaller of the maximum size
+// and the body size limit of the route set by RouteLimits. Bodies over the
+// limits are rejected with a request entity too large problem, unsupported
+// encodings with an unsupported media type problem.
+func (d *Decompressor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == encodingIdentity || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !generics.Contains(decodings, encoding) {
+			problem.Error(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf(errUnsupportedEncoding, encoding))
+			return
+		}
+		in := &countingReader{r: r.Body}
+		max := d.maxSize
+		if n, ok := r.Context().Value(maxBodyBytesKey).(int64); ok && n < max {
+			max = n
+		}
+		body, err := d.decompress(encoding, in, max)
+		var le limitError
+		var mbe *http.MaxBytesError
+		switch {
+		case errors.As(err, &le):
+			problem.Error(w, r, http.StatusRequestEntityTooLarge, le.Error())
+			return
+		case errors.As(err, &mbe):
+			problem.Error(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf(errRequestTooBig, mbe.Limit))
+			return
+		case err != nil:
+			problem.Error(w, r, http.StatusBadRequest, errDecompressBody)
+			return
+		}
+		r.Header.Del("Content-Encoding")
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		r.ContentLength = int64(len(body))
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decompress decompresses a body of at most max bytes, enforcing the ratio
+// limit.
+func (d *Decompressor) decompress(encoding string, in *countingReader, max int64) ([]byte, error) {
+	dec, err := newDecoder(encoding, in)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close() //nolint:errcheck
+	var buf bytes.Buffer
+	chunk := make([]byte, 32<<10)
+	for {
+		n, err := dec.Read(chunk)
+		buf.Write(chunk[:n])
+		out := int64(buf.Len())
+		if out > max {
+			return nil, limitError(fmt.Sprintf(errBodyTooLarge, max))
+		}
+		if out > ratioMinSize && in.n > 0 && float64(out)/float64(in.n) > d.maxRatio {
+			return nil, limitError(fmt.Sprintf(errBodyRatio, d.maxRatio))
+		}
+		if errors.Is(err, io.EOF) {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// A limitError indicates a body exceeded a decompression limit.
+type limitError string
+
+func (e limitError) Error() string { return string(e) }
+
+// decodings are the supported request content encodings.
+var decodings = []string{EncodingGzip, "x-gzip", EncodingZstd, EncodingBrotli}
+
+// newDecoder returns a decoder for the supplied content encoding.
+func newDecoder(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case EncodingGzip, "x-gzip":
+		g, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.Wrap(err, errDecompressBody)
+		}
+		return g, nil
+	case EncodingZstd:
+		z, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, errors.Wrap(err, errDecompressBody)
+		}
+		return z.IOReadCloser(), nil
+	case EncodingBrotli:
+		return io.NopCloser(brotli.NewReader(r)), nil
+	}
+	return nil, errors.Errorf(errUnsupportedEncoding, encoding)
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
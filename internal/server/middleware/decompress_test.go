@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestDecompressorHandler(t *testing.T) {
+	type arguments struct {
+		encoding string
+		body     []byte
+	}
+	type want struct {
+		status int
+		body   string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Identity": {
+			reason: "Bodies without an encoding should be passed on as is.",
+			args:   arguments{body: []byte(`{"a":1}`)},
+			want:   want{status: http.StatusOK, body: `{"a":1}`},
+		},
+		"Gzip": {
+			reason: "Gzip bodies should be decompressed.",
+			args:   arguments{encoding: EncodingGzip, body: gzipped(t, `{"a":1}`)},
+			want:   want{status: http.StatusOK, body: `{"a":1}`},
+		},
+		"Zstd": {
+			reason: "Zstd bodies should be decompressed.",
+			args:   arguments{encoding: EncodingZstd, body: zstded(t, `{"a":1}`)},
+			want:   want{status: http.StatusOK, body: `{"a":1}`},
+		},
+		"TooLarge": {
+			reason: "Bodies over the maximum decompressed size should be rejected.",
+			args:   arguments{encoding: EncodingGzip, body: gzipped(t, random(300<<10))},
+			want:   want{status: http.StatusRequestEntityTooLarge},
+		},
+		"Ratio": {
+			reason: "Bodies over the maximum compression ratio should be rejected.",
+			args:   arguments{encoding: EncodingZstd, body: zstded(t, strings.Repeat("a", 128<<10))},
+			want:   want{status: http.StatusRequestEntityTooLarge},
+		},
+		"Malformed": {
+			reason: "Bodies that cannot be decompressed should be rejected.",
+			args:   arguments{encoding: EncodingGzip, body: []byte("not gzip")},
+			want:   want{status: http.StatusBadRequest},
+		},
+		"Unsupported": {
+			reason: "Bodies with an unsupported encoding should be rejected.",
+			args:   arguments{encoding: "compress", body: []byte("x")},
+			want:   want{status: http.StatusUnsupportedMediaType},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []byte
+			h := NewDecompressor(DecompressorWithMaxSize(256<<10), DecompressorWithMaxRatio(100)).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = io.ReadAll(r.Body)
+			}))
+			req, _ := http.NewRequestWithContext(context.Background(), "POST", "/v1/demo", bytes.NewReader(tc.args.body))
+			if tc.args.encoding != "" {
+				req.Header.Set("Content-Encoding", tc.args.encoding)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			if diff := cmp.Diff(tc.want, want{status: rr.Code, body: string(got)}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nHandler(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write([]byte(s))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func random(n int) string {
+	b := make([]byte, n)
+	r := rand.New(rand.NewSource(1)) //nolint:gosec // deterministic test data.
+	for i := range b {
+		b[i] = byte('a' + r.Intn(26))
+	}
+	return string(b)
+}
+
+func zstded(t *testing.T, s string) []byte {
+	t.Helper()
+	e, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e.EncodeAll([]byte(s), nil)
+}
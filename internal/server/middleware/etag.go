@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/upbound/build-submodule-demo/internal/server/conditional"
+)
+
+// ETagger is middleware that answers conditional GET and HEAD requests.
+// Successful responses without an ETag are given a strong ETag computed from
+// their body, as long as they are no larger than the maximum size and are not
+// streamed. Responses with validators set by their handler, for example with
+// conditional.SetValidators, are evaluated without being buffered. Handlers
+// of unsafe requests should evaluate If-Match themselves with
+// conditional.Check, as only they know the current state of the resource.
+type ETagger struct {
+	maxSize int
+}
+
+// ETaggerOpt modifies ETag middleware.
+type ETaggerOpt func(e *ETagger)
+
+// ETaggerWithMaxSize sets the maximum size of responses that are buffered to
+// compute their ETag.
+func ETaggerWithMaxSize(n int) ETaggerOpt {
+	return func(e *ETagger) {
+		e.maxSize = n
+	}
+}
+
+// NewETagger constructs new ETag middleware.
+func NewETagger(opts ...ETaggerOpt) *ETagger {
+	e := &ETagger{
+		maxSize: 1 << 20,
+	}
+	for _, o := range opts {
+		o(e)
+	}
+	return e
+}
+
+// Handler sets ETags on, and evaluates the preconditions of, GET and HEAD
+// requests.
+func (e *ETagger) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ew := &etagWriter{ResponseWriter: w, e: e, r: r, status: http.StatusOK}
+		next.ServeHTTP(ew, r)
+		ew.finish()
+	})
+}
+
+// etagWriter buffers a successful response to compute its ETag, then writes
+// it, or a response to its preconditions.
+type etagWriter struct {
+	http.ResponseWriter
+	e *ETagger
+	r *http.Request
+
+	status      int
+	wroteHeader bool
+	buffer      bool
+	discard     bool
+	body        bytes.Buffer
+}
+
+func (w *etagWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	h := w.Header()
+	switch {
+	case status != http.StatusOK:
+		w.ResponseWriter.WriteHeader(status)
+	case h.Get(conditional.HeaderETag) != "" || h.Get(conditional.HeaderLastModified) != "":
+		w.respond()
+	default:
+		w.buffer = true
+	}
+}
+
+func (w *etagWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	switch {
+	case w.discard:
+		return len(p), nil
+	case !w.buffer:
+		return w.ResponseWriter.Write(p)
+	}
+	n, _ := w.body.Write(p)
+	if w.body.Len() > w.e.maxSize {
+		w.passthrough()
+	}
+	return n, nil
+}
+
+// Flush writes buffered data. Responses flushed before they are complete are
+// treated as streams and are not given an ETag.
+func (w *etagWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+	if w.buffer {
+		w.passthrough()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.discard {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer.
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// passthrough writes a buffered response as is.
+func (w *etagWriter) passthrough() {
+	w.buffer = false
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	w.body.Reset()
+}
+
+// respond evaluates the preconditions of the request against the validators
+// of the response, and writes the status the response should have. The body
+// is discarded unless the request should be processed.
+func (w *etagWriter) respond() {
+	h := w.Header()
+	modified, _ := http.ParseTime(h.Get(conditional.HeaderLastModified))
+	if status := conditional.Evaluate(w.r, h.Get(conditional.HeaderETag), modified); status != 0 {
+		w.discard = true
+		conditional.Respond(w.ResponseWriter, w.r, status)
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// finish computes the ETag of a buffered response and writes it.
+func (w *etagWriter) finish() {
+	if !w.wroteHeader || !w.buffer {
+		return
+	}
+	w.buffer = false
+	body := w.body.Bytes()
+	w.Header().Set(conditional.HeaderETag, conditional.HashETag(body))
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.respond()
+	if !w.discard {
+		_, _ = w.ResponseWriter.Write(body)
+	}
+}
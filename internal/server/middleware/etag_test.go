@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/build-submodule-demo/internal/server/conditional"
+)
+
+func TestETaggerHandler(t *testing.T) {
+	hello := conditional.HashETag([]byte("hello"))
+	type arguments struct {
+		method      string
+		ifNoneMatch string
+		ifMatch     string
+		etag        string
+		body        string
+	}
+	type want struct {
+		status int
+		etag   string
+		body   string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Computed": {
+			reason: "Responses without an ETag should be given one computed from their body.",
+			args:   arguments{method: http.MethodGet, body: "hello"},
+			want:   want{status: http.StatusOK, etag: hello, body: "hello"},
+		},
+		"NotModified": {
+			reason: "Requests for a matching computed ETag should not be modified.",
+			args:   arguments{method: http.MethodGet, ifNoneMatch: hello, body: "hello"},
+			want:   want{status: http.StatusNotModified, etag: hello},
+		},
+		"HandlerETag": {
+			reason: "ETags set by handlers should be kept and evaluated.",
+			args:   arguments{method: http.MethodGet, ifNoneMatch: `"v1"`, etag: `"v1"`, body: "hello"},
+			want:   want{status: http.StatusNotModified, etag: `"v1"`},
+		},
+		"PreconditionFailed": {
+			reason: "Requests for a different ETag with If-Match should fail.",
+			args:   arguments{method: http.MethodGet, ifMatch: `"v0"`, body: "hello"},
+			want:   want{status: http.StatusPreconditionFailed},
+		},
+		"TooLarge": {
+			reason: "Responses over the maximum size should not be given an ETag.",
+			args:   arguments{method: http.MethodGet, body: "hello world"},
+			want:   want{status: http.StatusOK, body: "hello world"},
+		},
+		"Unsafe": {
+			reason: "Responses to unsafe requests should not be given an ETag.",
+			args:   arguments{method: http.MethodPost, body: "hello"},
+			want:   want{status: http.StatusOK, body: "hello"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := NewETagger(ETaggerWithMaxSize(8)).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.args.etag != "" {
+					w.Header().Set(conditional.HeaderETag, tc.args.etag)
+				}
+				_, _ = w.Write([]byte(tc.args.body))
+			}))
+			req := httptest.NewRequest(tc.args.method, "/v1/demo", nil)
+			if tc.args.ifNoneMatch != "" {
+				req.Header.Set(conditional.HeaderIfNoneMatch, tc.args.ifNoneMatch)
+			}
+			if tc.args.ifMatch != "" {
+				req.Header.Set(conditional.HeaderIfMatch, tc.args.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			got := want{status: rr.Code, etag: rr.Header().Get(conditional.HeaderETag)}
+			if rr.Code != http.StatusPreconditionFailed {
+				got.body = rr.Body.String()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nHandler(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
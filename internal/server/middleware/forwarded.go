@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+)
+
+// Forwarded determines the client IP, scheme and host of requests from the
+// forwarding headers set by the supplied trusted proxies, and stores them in
+// the request context. The host is also stored as the original domain.
+// Forwarding headers from untrusted peers are ignored. It must be used before
+// middleware that logs or measures requests.
+func Forwarded(trusted []*net.IPNet) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			i := forwarded.Parse(r, trusted)
+			r = SetDomainInContext(r, i.Host)
+			next.ServeHTTP(w, r.WithContext(forwarded.NewContext(r.Context(), i)))
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal/idempotency"
+	"github.com/upbound/build-submodule-demo/internal/protocol"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errIdempotencyStore    = "Failed to use idempotency store, processing request without replay protection."
+	errIdempotencyKey      = "Idempotency-Key must be between 1 and 255 characters"
+	errIdempotencyReadBody = "failed to read request body"
+	errIdempotencyInFlight = "a request with this Idempotency-Key is still being processed"
+	errIdempotencyMismatch = "Idempotency-Key was already used with a different request"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// Idempotency is middleware that makes unsafe requests with an
+// Idempotency-Key header safe to retry. The first response for a key is
+// stored and replayed to repeats of the request by the same principal. Keys
+// reused while the first request is in flight are rejected with a conflict
+// problem, and keys reused with a different request with an unprocessable
+// entity problem. Server errors are not stored, so that requests that failed
+// may be retried.
+type Idempotency struct {
+	log   logging.Logger
+	store idempotency.Store
+	ttl   time.Duration
+	lock  time.Duration
+}
+
+// IdempotencyOpt modifies idempotency middleware.
+type IdempotencyOpt func(i *Idempotency)
+
+// IdempotencyWithLogger sets the logger for idempotency middleware.
+func IdempotencyWithLogger(l logging.Logger) IdempotencyOpt {
+	return func(i *Idempotency) {
+		i.log = l
+	}
+}
+
+// IdempotencyWithTTL sets how long responses are replayed for.
+func IdempotencyWithTTL(d time.Duration) IdempotencyOpt {
+	return func(i *Idempotency) {
+		i.ttl = d
+	}
+}
+
+// IdempotencyWithLockTimeout sets how long a key is held by a request that
+// does not complete, e.g. because the replica serving it stopped.
+func IdempotencyWithLockTimeout(d time.Duration) IdempotencyOpt {
+	return func(i *Idempotency) {
+		i.lock = d
+	}
+}
+
+// NewIdempotency constructs new idempotency middleware.
+func NewIdempotency(store idempotency.Store, opts ...IdempotencyOpt) *Idempotency {
+	i := &Idempotency{
+		log:   logging.NewNopLogger(),
+		store: store,
+		ttl:   24 * time.Hour,
+		lock:  time.Minute,
+	}
+	for _, o := range opts {
+		o(i)
+	}
+	return i
+}
+
+// Handler replays or processes unsafe requests with an Idempotency-Key. It
+// must be used after authentication to scope keys by principal.
+func (i *Idempotency) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ik, ok := r.Header[protocol.HeaderIdempotencyKey]
+		if !ok || safeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(ik) != 1 || ik[0] == "" || len(ik[0]) > maxIdempotencyKeyLength {
+			problem.Error(w, r, http.StatusBadRequest, errIdempotencyKey)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		var mbe *http.MaxBytesError
+		switch {
+		case errors.As(err, &mbe):
+			problem.Error(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf(errRequestTooBig, mbe.Limit))
+			return
+		case err != nil:
+			problem.Error(w, r, http.StatusBadRequest, errIdempotencyReadBody)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		entity, id := principal(r)
+		key := entity + ":" + id + ":" + ik[0]
+		fp := fingerprint(r, body)
+		rec, started, err := i.store.Begin(r.Context(), key, fp, i.lock)
+		if err != nil {
+			i.log.Info(errIdempotencyStore, "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch {
+		case !started && rec.Fingerprint != fp:
+			problem.Error(w, r, http.StatusUnprocessableEntity, errIdempotencyMismatch)
+			return
+		case !started && rec.Response == nil:
+			w.Header().Set("Retry-After", seconds(time.Second))
+			problem.Error(w, r, http.StatusConflict, errIdempotencyInFlight)
+			return
+		case !started:
+			replay(w, rec.Response)
+			return
+		}
+
+		rw := &recordWriter{ResponseWriter: w, status: http.StatusOK, outer: w.Header().Clone()}
+		completed := false
+		defer func() {
+			// The key is released if the handler panicked or failed, so
+			// that the request may be retried.
+			if completed {
+				return
+			}
+			if err := i.store.Abort(r.Context(), key, rec.Owner); err != nil {
+				i.log.Info(errIdempotencyStore, "error", err)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+		if !rw.wroteHeader {
+			rw.header = rw.handlerHeader()
+		}
+		if rw.status >= http.StatusInternalServerError {
+			return
+		}
+		res := idempotency.Response{Status: rw.status, Header: rw.header, Body: rw.body.Bytes()}
+		if err := i.store.Complete(r.Context(), key, rec.Owner, res, i.ttl); err != nil {
+			i.log.Info(errIdempotencyStore, "error", err)
+			return
+		}
+		completed = true
+	})
+}
+
+// fingerprint identifies a request by its method, path and body.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes a stored response. Headers already set for this request, such
+// as its request ID, take precedence over stored ones.
+func replay(w http.ResponseWriter, res *idempotency.Response) {
+	h := w.Header()
+	for k, v := range res.Header {
+		if _, ok := h[k]; !ok {
+			h[k] = v
+		}
+	}
+	h.Set(protocol.HeaderIdempotentReplayed, "true")
+	w.WriteHeader(res.Status)
+	_, _ = w.Write(res.Body)
+}
+
+// recordWriter writes a response while recording it. Only headers set by the
+// handler are recorded; those set by outer middleware, such as rate limits,
+// request IDs and cookies, belong to the request that set them.
+type recordWriter struct {
+	http.ResponseWriter
+
+	outer       http.Header
+	wroteHeader bool
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+}
+
+// handlerHeader returns the headers that were added or changed since the
+// handler was called.
+func (w *recordWriter) handlerHeader() http.Header {
+	h := http.Header{}
+	for k, v := range w.Header() {
+		if !slices.Equal(w.outer[k], v) {
+			h[k] = slices.Clone(v)
+		}
+	}
+	return h
+}
+
+func (w *recordWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	w.header = w.handlerHeader()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the underlying response writer.
+func (w *recordWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/build-submodule-demo/internal/idempotency"
+	"github.com/upbound/build-submodule-demo/internal/protocol"
+)
+
+func TestIdempotencyHandler(t *testing.T) {
+	type request struct {
+		key  string
+		body string
+	}
+	type want struct {
+		status   int
+		body     string
+		replayed bool
+		calls    int
+	}
+	cases := map[string]struct {
+		reason   string
+		first    request
+		second   request
+		status   int
+		inflight bool
+		want     want
+	}{
+		"Replayed": {
+			reason: "A repeated request should be replayed without calling the handler.",
+			first:  request{key: "a", body: "x"},
+			second: request{key: "a", body: "x"},
+			status: http.StatusCreated,
+			want:   want{status: http.StatusCreated, body: "created", replayed: true, calls: 1},
+		},
+		"DifferentKey": {
+			reason: "Requests with different keys should both be handled.",
+			first:  request{key: "a", body: "x"},
+			second: request{key: "b", body: "x"},
+			status: http.StatusCreated,
+			want:   want{status: http.StatusCreated, body: "created", calls: 2},
+		},
+		"NoKey": {
+			reason: "Requests without a key should always be handled.",
+			first:  request{body: "x"},
+			second: request{body: "x"},
+			status: http.StatusCreated,
+			want:   want{status: http.StatusCreated, body: "created", calls: 2},
+		},
+		"Mismatch": {
+			reason: "A key reused with a different body should be rejected.",
+			first:  request{key: "a", body: "x"},
+			second: request{key: "a", body: "y"},
+			status: http.StatusCreated,
+			want:   want{status: http.StatusUnprocessableEntity, calls: 1},
+		},
+		"ServerError": {
+			reason: "Requests that failed should be handled again when retried.",
+			first:  request{key: "a", body: "x"},
+			second: request{key: "a", body: "x"},
+			status: http.StatusInternalServerError,
+			want:   want{status: http.StatusInternalServerError, body: "created", calls: 2},
+		},
+		"InFlight": {
+			reason: "A key reused while its request is in flight should be rejected.",
+			first:  request{key: "a", body: "x"},
+			second: request{key: "a", body: "x"},
+			status: http.StatusCreated,
+			// The second request is made by the handler of the first.
+			inflight: true,
+			want:     want{status: http.StatusConflict, calls: 1},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			var h http.Handler
+			var inner *httptest.ResponseRecorder
+			do := func(rq request) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/v1/demo", strings.NewReader(rq.body))
+				if rq.key != "" {
+					req.Header.Set(protocol.HeaderIdempotencyKey, rq.key)
+				}
+				rr := httptest.NewRecorder()
+				h.ServeHTTP(rr, req)
+				return rr
+			}
+			h = NewIdempotency(idempotency.NewMemoryStore()).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if tc.inflight && calls == 1 {
+					inner = do(tc.second)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte("created"))
+			}))
+			do(tc.first)
+			rr := inner
+			if !tc.inflight {
+				rr = do(tc.second)
+			}
+			got := want{status: rr.Code, replayed: rr.Header().Get(protocol.HeaderIdempotentReplayed) == "true", calls: calls}
+			if !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/problem+json") {
+				got.body = rr.Body.String()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nHandler(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestIdempotencyReplayHeader(t *testing.T) {
+	calls := 0
+	outer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(10-calls))
+			if calls == 1 {
+				w.Header().Set("Set-Cookie", "csrf_token=first")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	h := outer(NewIdempotency(idempotency.NewMemoryStore()).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/v1/demo/1")
+		w.WriteHeader(http.StatusCreated)
+	})))
+	var rr *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/v1/demo", strings.NewReader("x"))
+		req.Header.Set(protocol.HeaderIdempotencyKey, "a")
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+	}
+	want := http.Header{
+		"Location":                        {"/v1/demo/1"},
+		"Ratelimit-Remaining":             {"8"},
+		protocol.HeaderIdempotentReplayed: {"true"},
+	}
+	if diff := cmp.Diff(want, rr.Header()); diff != "" {
+		t.Errorf("\nHandler(...): headers set by outer middleware should not be replayed: -want, +got:\n%s", diff)
+	}
+}
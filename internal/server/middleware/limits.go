@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	chimid "github.com/go-chi/chi/v5/middleware"
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errParseLimits   = "failed to parse limits of operation %s %s"
+	errBuildRouter   = "failed to build router for route limits"
+	errRequestTooBig = "request body exceeds %d bytes"
+	errDeadline      = "request did not complete within %s"
+)
+
+// OpenAPI operation extensions overriding the route limits.
+const (
+	// ExtensionMaxBodyBytes is the maximum size in bytes of the request body
+	// of an operation. 0 is unlimited.
+	ExtensionMaxBodyBytes = "x-max-body-bytes"
+
+	// ExtensionTimeout is the deadline of an operation as a duration, e.g.
+	// 30s. A deadline of 0 is unlimited, and also lifts the server read and
+	// write timeouts, so that the operation may stream its request or
+	// response.
+	ExtensionTimeout = "x-timeout"
+)
+
+// timeoutGrace is the time allowed to write a response once a request
+// deadline passes.
+const timeoutGrace = time.Second
+
+// routeLimit is the body size limit and deadline of a route.
+type routeLimit struct {
+	maxBodyBytes int64
+	timeout      time.Duration
+}
+
+// RouteLimits is middleware that limits the size of request bodies and sets
+// a deadline on request contexts. Limits default to those of the server and
+// may be overridden per operation with the x-max-body-bytes and x-timeout
+// OpenAPI extensions.
+type RouteLimits struct {
+	router   routers.Router
+	defaults routeLimit
+	routes   map[*openapi3.Operation]routeLimit
+}
+
+// RouteLimitsOpt modifies route limits middleware.
+type RouteLimitsOpt func(l *RouteLimits)
+
+// RouteLimitsWithMaxBodyBytes sets the default maximum size in bytes of
+// request bodies. 0 is unlimited.
+func RouteLimitsWithMaxBodyBytes(n int64) RouteLimitsOpt {
+	return func(l *RouteLimits) {
+		l.defaults.maxBodyBytes = n
+	}
+}
+
+// RouteLimitsWithTimeout sets the default deadline of requests. 0 is
+// unlimited.
+func RouteLimitsWithTimeout(d time.Duration) RouteLimitsOpt {
+	return func(l *RouteLimits) {
+		l.defaults.timeout = d
+	}
+}
+
+// NewRouteLimits constructs new route limits middleware. Overrides are read
+// from the operations of the supplied spec, which may be nil if the server
+// has none.
+func NewRouteLimits(swagger *openapi3.T, opts ...RouteLimitsOpt) (*RouteLimits, error) {
+	l := &RouteLimits{
+		routes: map[*openapi3.Operation]routeLimit{},
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	if swagger == nil {
+		return l, nil
+	}
+	for path, item := range swagger.Paths {
+		for method, op := range item.Operations() {
+			rl, err := l.parse(op)
+			if err != nil {
+				return nil, errors.Wrapf(err, errParseLimits, method, path)
+			}
+			l.routes[op] = rl
+		}
+	}
+	router, err := gorillamux.NewRouter(swagger)
+	if err != nil {
+		return nil, errors.Wrap(err, errBuildRouter)
+	}
+	l.router = router
+	return l, nil
+}
+
+// parse returns the limits of an operation.
+func (l *RouteLimits) parse(op *openapi3.Operation) (routeLimit, error) {
+	rl := l.defaults
+	if v, ok := op.Extensions[ExtensionMaxBodyBytes]; ok {
+		if err := decodeExtension(v, &rl.maxBodyBytes); err != nil {
+			return rl, errors.Wrap(err, ExtensionMaxBodyBytes)
+		}
+	}
+	if v, ok := op.Extensions[ExtensionTimeout]; ok {
+		var s string
+		if err := decodeExtension(v, &s); err != nil {
+			return rl, errors.Wrap(err, ExtensionTimeout)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return rl, errors.Wrap(err, ExtensionTimeout)
+		}
+		rl.timeout = d
+	}
+	return rl, nil
+}
+
+// limit returns the limits of the route matching a request.
+func (l *RouteLimits) limit(r *http.Request) (rl routeLimit, override bool) {
+	if l.router == nil {
+		return l.defaults, false
+	}
+	route, _, err := l.router.FindRoute(r)
+	if err != nil {
+		return l.defaults, false
+	}
+	rl, ok := l.routes[route.Operation]
+	if !ok {
+		return l.defaults, false
+	}
+	_, bodyOverride := route.Operation.Extensions[ExtensionMaxBodyBytes]
+	_, timeoutOverride := route.Operation.Extensions[ExtensionTimeout]
+	return rl, bodyOverride || timeoutOverride
+}
+
+// Handler limits the request body and sets the request deadline. Requests
+// declaring a body over the limit are rejected with a request entity too
+// large problem; bodies that exceed it while being read fail with an
+// *http.MaxBytesError. The limit is also applied by the Decompressor to
+// decompressed bodies. Requests that are not answered by their deadline are
+// answered with a gateway timeout problem.
+func (l *RouteLimits) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rl, override := l.limit(r)
+		if rl.maxBodyBytes > 0 && r.Body != nil && r.Body != http.NoBody {
+			if r.ContentLength > rl.maxBodyBytes {
+				problem.Error(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf(errRequestTooBig, rl.maxBodyBytes))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, rl.maxBodyBytes)
+			r = r.WithContext(context.WithValue(r.Context(), maxBodyBytesKey, rl.maxBodyBytes))
+		}
+		if override {
+			// The server timeouts apply to the connection, so they are
+			// replaced by those of the route. This is best effort; writers
+			// that cannot be unwrapped keep the server timeouts.
+			rc := http.NewResponseController(w)
+			deadline := time.Time{}
+			if rl.timeout > 0 {
+				deadline = time.Now().Add(rl.timeout + timeoutGrace)
+			}
+			_ = rc.SetReadDeadline(deadline)
+			_ = rc.SetWriteDeadline(deadline)
+		}
+		if rl.timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), rl.timeout)
+		defer cancel()
+		ww := chimid.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+		if ww.Status() == 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			problem.Error(ww, r, http.StatusGatewayTimeout, fmt.Sprintf(errDeadline, rl.timeout))
+		}
+	})
+}
+
+// decodeExtension decodes the value of an OpenAPI extension, which is raw
+// JSON when the spec was loaded from a document.
+func decodeExtension(v any, into any) error {
+	b, ok := v.(json.RawMessage)
+	if !ok {
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(b, into)
+}
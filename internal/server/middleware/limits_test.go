@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/go-cmp/cmp"
+)
+
+func limitsSpec() *openapi3.T {
+	op := func(ext map[string]interface{}) *openapi3.Operation {
+		return &openapi3.Operation{
+			ExtensionProps: openapi3.ExtensionProps{Extensions: ext},
+			Responses:      openapi3.NewResponses(),
+		}
+	}
+	return &openapi3.T{
+		OpenAPI: "3.0.0",
+		Info:    &openapi3.Info{Title: "limits", Version: "1"},
+		Paths: openapi3.Paths{
+			"/upload": &openapi3.PathItem{Post: op(map[string]interface{}{
+				ExtensionMaxBodyBytes: json.RawMessage(`64`),
+			})},
+			"/slow": &openapi3.PathItem{Get: op(map[string]interface{}{
+				ExtensionTimeout: json.RawMessage(`"10ms"`),
+			})},
+			"/stream": &openapi3.PathItem{Get: op(map[string]interface{}{
+				ExtensionTimeout: json.RawMessage(`"0"`),
+			})},
+		},
+	}
+}
+
+func TestRouteLimitsHandler(t *testing.T) {
+	type arguments struct {
+		method string
+		path   string
+		body   string
+	}
+	type want struct {
+		status   int
+		deadline bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"WithinDefault": {
+			reason: "Bodies within the default limit should be accepted, with the default deadline.",
+			args:   arguments{method: http.MethodPost, path: "/other", body: strings.Repeat("a", 16)},
+			want:   want{status: http.StatusOK, deadline: true},
+		},
+		"OverDefault": {
+			reason: "Bodies over the default limit should be rejected.",
+			args:   arguments{method: http.MethodPost, path: "/other", body: strings.Repeat("a", 33)},
+			want:   want{status: http.StatusRequestEntityTooLarge},
+		},
+		"WithinRoute": {
+			reason: "Bodies within the limit of their route should be accepted.",
+			args:   arguments{method: http.MethodPost, path: "/upload", body: strings.Repeat("a", 64)},
+			want:   want{status: http.StatusOK, deadline: true},
+		},
+		"OverRoute": {
+			reason: "Bodies over the limit of their route should be rejected.",
+			args:   arguments{method: http.MethodPost, path: "/upload", body: strings.Repeat("a", 65)},
+			want:   want{status: http.StatusRequestEntityTooLarge},
+		},
+		"RouteDeadline": {
+			reason: "Requests not answered by the deadline of their route should time out.",
+			args:   arguments{method: http.MethodGet, path: "/slow"},
+			want:   want{status: http.StatusGatewayTimeout},
+		},
+		"Unlimited": {
+			reason: "Routes with a timeout of 0 should not have a deadline.",
+			args:   arguments{method: http.MethodGet, path: "/stream"},
+			want:   want{status: http.StatusOK},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			l, err := NewRouteLimits(limitsSpec(), RouteLimitsWithMaxBodyBytes(32), RouteLimitsWithTimeout(time.Minute))
+			if err != nil {
+				t.Fatalf("NewRouteLimits(...): %v", err)
+			}
+			h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/slow" {
+					<-r.Context().Done()
+					return
+				}
+				if _, err := io.ReadAll(r.Body); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if _, ok := r.Context().Deadline(); ok {
+					w.Header().Set("X-Deadline", "true")
+				}
+			}))
+			var body io.Reader = http.NoBody
+			if tc.args.body != "" {
+				body = strings.NewReader(tc.args.body)
+			}
+			req := httptest.NewRequest(tc.args.method, tc.args.path, body)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			got := want{status: rr.Code, deadline: rr.Header().Get("X-Deadline") == "true"}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nHandler(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewRouteLimits(t *testing.T) {
+	spec := limitsSpec()
+	spec.Paths["/slow"].Get.Extensions[ExtensionTimeout] = json.RawMessage(`"soon"`)
+	if _, err := NewRouteLimits(spec); err == nil {
+		t.Errorf("\nInvalid timeouts should be rejected.\nNewRouteLimits(...): want error, got nil")
+	}
+}
+
+func TestRouteLimitsUnknownLength(t *testing.T) {
+	l, err := NewRouteLimits(nil, RouteLimitsWithMaxBodyBytes(4))
+	if err != nil {
+		t.Fatalf("NewRouteLimits(...): %v", err)
+	}
+	var got error
+	h := l.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, got = io.ReadAll(r.Body)
+	}))
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/", io.NopCloser(strings.NewReader("too long")))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	var mbe *http.MaxBytesError
+	if !errors.As(got, &mbe) {
+		t.Errorf("\nBodies of unknown length should fail once over the limit.\nReadAll(...): want *http.MaxBytesError, got %v", got)
+	}
+}
+
+func TestRouteLimitsDecompressed(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		body   string
+		want   int
+	}{
+		"Within": {
+			reason: "Bodies that decompress to within the limit should be accepted.",
+			body:   strings.Repeat("a", 1024),
+			want:   http.StatusOK,
+		},
+		"Over": {
+			reason: "Bodies that decompress to over the limit should be rejected, even if their compressed size is within it.",
+			body:   strings.Repeat("a", 1025),
+			want:   http.StatusRequestEntityTooLarge,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			l, err := NewRouteLimits(nil, RouteLimitsWithMaxBodyBytes(1024))
+			if err != nil {
+				t.Fatalf("NewRouteLimits(...): %v", err)
+			}
+			h := l.Handler(NewDecompressor().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/", bytes.NewReader(gzipped(t, tc.body)))
+			req.Header.Set("Content-Encoding", EncodingGzip)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			if diff := cmp.Diff(tc.want, rr.Code); diff != "" {
+				t.Errorf("\n%s\nHandler(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
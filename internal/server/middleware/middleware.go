@@ -20,6 +20,10 @@ const (
 
 	// OriginalDomainKey is the key for the original request domain before modification.
 	OriginalDomainKey ctxkey = 2
+
+	// maxBodyBytesKey is the key for the body size limit of the request's
+	// route, which also bounds decompressed bodies.
+	maxBodyBytesKey ctxkey = 3
 )
 
 // OriginalPathFromContext extracts original path from the supplied context.
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+// An OCIErrorCode is an error code defined by the OCI distribution spec.
+type OCIErrorCode string
+
+// OCI distribution spec error codes.
+const (
+	OCIBlobUnknown         OCIErrorCode = "BLOB_UNKNOWN"
+	OCIBlobUploadInvalid   OCIErrorCode = "BLOB_UPLOAD_INVALID"
+	OCIBlobUploadUnknown   OCIErrorCode = "BLOB_UPLOAD_UNKNOWN"
+	OCIDigestInvalid       OCIErrorCode = "DIGEST_INVALID"
+	OCIManifestBlobUnknown OCIErrorCode = "MANIFEST_BLOB_UNKNOWN"
+	OCIManifestInvalid     OCIErrorCode = "MANIFEST_INVALID"
+	OCIManifestUnknown     OCIErrorCode = "MANIFEST_UNKNOWN"
+	OCINameInvalid         OCIErrorCode = "NAME_INVALID"
+	OCINameUnknown         OCIErrorCode = "NAME_UNKNOWN"
+	OCISizeInvalid         OCIErrorCode = "SIZE_INVALID"
+	OCIUnauthorized        OCIErrorCode = "UNAUTHORIZED"
+	OCIDenied              OCIErrorCode = "DENIED"
+	OCIUnsupported         OCIErrorCode = "UNSUPPORTED"
+	OCITooManyRequests     OCIErrorCode = "TOOMANYREQUESTS"
+)
+
+// ErrDenied may be returned, optionally wrapped, by an OpenAPI authentication
+// function to indicate that the caller is authenticated but not permitted to
+// access the resource.
+var ErrDenied = errors.New("requested access to the resource is denied")
+
+// An OCIError is a single error as defined by the OCI distribution spec.
+type OCIError struct {
+	Code    OCIErrorCode `json:"code"`
+	Message string       `json:"message"`
+	Detail  any          `json:"detail,omitempty"`
+}
+
+type ociErrors struct {
+	Errors []OCIError `json:"errors"`
+}
+
+// OCIOptions customize the OCI errors returned for invalid requests.
+type OCIOptions struct {
+	// ParameterCodes maps parameter names to the error code returned when the
+	// parameter is invalid. Defaults to DefaultOCIParameterCodes.
+	ParameterCodes map[string]OCIErrorCode
+
+	// BodyCode is returned when the request body is invalid. Defaults to
+	// MANIFEST_INVALID.
+	BodyCode OCIErrorCode
+}
+
+// DefaultOCIParameterCodes map the parameters of the OCI distribution API to
+// their error codes.
+var DefaultOCIParameterCodes = map[string]OCIErrorCode{
+	"name":      OCINameInvalid,
+	"digest":    OCIDigestInvalid,
+	"reference": OCIManifestInvalid,
+	"tag":       OCIManifestInvalid,
+}
+
+// ociMessages are the messages defined by the OCI distribution spec.
+var ociMessages = map[OCIErrorCode]string{
+	OCIBlobUnknown:         "blob unknown to registry",
+	OCIBlobUploadInvalid:   "blob upload invalid",
+	OCIBlobUploadUnknown:   "blob upload unknown to registry",
+	OCIDigestInvalid:       "provided digest did not match uploaded content",
+	OCIManifestBlobUnknown: "manifest references a manifest or blob unknown to registry",
+	OCIManifestInvalid:     "manifest invalid",
+	OCIManifestUnknown:     "manifest unknown to registry",
+	OCINameInvalid:         "invalid repository name",
+	OCINameUnknown:         "repository name not known to registry",
+	OCISizeInvalid:         "provided length did not match content length",
+	OCIUnauthorized:        "authentication required",
+	OCIDenied:              "requested access to the resource is denied",
+	OCIUnsupported:         "the operation is unsupported",
+	OCITooManyRequests:     "too many requests",
+}
+
+// NewOCIError constructs an OCI error with the message defined by the spec.
+func NewOCIError(code OCIErrorCode, detail any) OCIError {
+	return OCIError{Code: code, Message: ociMessages[code], Detail: detail}
+}
+
+// WriteOCIErrors renders errors in the format defined by the OCI distribution
+// spec.
+func WriteOCIErrors(w http.ResponseWriter, status int, errs ...OCIError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(&ociErrors{Errors: errs})
+}
+
+// errorsFromValidationError maps kin-openapi validation failures to OCI
+// errors and the status they should be returned with. Errors that are not
+// validation failures are returned as internal server errors, for which the
+// OCI distribution spec defines no error code.
+func (o OCIOptions) errorsFromValidationError(err error) (int, []OCIError) {
+	var (
+		secErr   *openapi3filter.SecurityRequirementsError
+		reqErr   *openapi3filter.RequestError
+		multiErr openapi3.MultiError
+		sizeErr  *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &sizeErr):
+		return http.StatusRequestEntityTooLarge, []OCIError{NewOCIError(OCISizeInvalid, err.Error())}
+	case errors.Is(err, routers.ErrPathNotFound):
+		return http.StatusNotFound, []OCIError{NewOCIError(OCINameUnknown, err.Error())}
+	case errors.Is(err, routers.ErrMethodNotAllowed):
+		return http.StatusMethodNotAllowed, []OCIError{NewOCIError(OCIUnsupported, err.Error())}
+	case errors.As(err, &secErr):
+		for _, e := range secErr.Errors {
+			if errors.Is(e, ErrDenied) {
+				return http.StatusForbidden, []OCIError{NewOCIError(OCIDenied, nil)}
+			}
+		}
+		return http.StatusUnauthorized, []OCIError{NewOCIError(OCIUnauthorized, nil)}
+	case !errors.As(err, &multiErr) && !errors.As(err, &reqErr):
+		return http.StatusInternalServerError, []OCIError{}
+	}
+
+	params := o.ParameterCodes
+	if params == nil {
+		params = DefaultOCIParameterCodes
+	}
+	body := o.BodyCode
+	if body == "" {
+		body = OCIManifestInvalid
+	}
+	fes := problem.FieldErrors(err)
+	errs := make([]OCIError, 0, len(fes))
+	for _, fe := range fes {
+		code := OCIUnsupported
+		switch {
+		case fe.In == "body":
+			code = body
+		case params[fe.Field] != "":
+			code = params[fe.Field]
+		}
+		errs = append(errs, NewOCIError(code, fe))
+	}
+	return http.StatusBadRequest, errs
+}
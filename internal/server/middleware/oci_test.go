@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/google/go-cmp/cmp"
+)
+
+const registrySpec = `
+openapi: 3.0.0
+info:
+  title: Registry
+  version: '1.0'
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+paths:
+  /v2/{name}/manifests/{reference}:
+    parameters:
+      - name: name
+        in: path
+        required: true
+        schema:
+          type: string
+          pattern: '^[a-z0-9]+$'
+      - name: reference
+        in: path
+        required: true
+        schema:
+          type: string
+          pattern: '^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}$'
+    get:
+      security:
+        - bearer: []
+      responses:
+        '200':
+          description: OK
+    put:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [schemaVersion]
+              properties:
+                schemaVersion:
+                  type: integer
+      responses:
+        '201':
+          description: Created
+`
+
+func TestRequestValidatorWithOptionsOCI(t *testing.T) {
+	errBoom := errors.New("boom")
+	swagger, err := openapi3.NewLoader().LoadFromData([]byte(registrySpec))
+	if err != nil {
+		t.Fatalf("LoadFromData(...): unexpected error: %v", err)
+	}
+	type arguments struct {
+		method string
+		target string
+		body   string
+		auth   openapi3filter.AuthenticationFunc
+	}
+	type want struct {
+		status int
+		codes  []OCIErrorCode
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Valid": {
+			reason: "A valid request should be passed to the next handler.",
+			args: arguments{
+				method: http.MethodGet,
+				target: "/v2/repo/manifests/latest",
+			},
+			want: want{
+				status: http.StatusOK,
+			},
+		},
+		"NameInvalid": {
+			reason: "An invalid repository name should be rejected with NAME_INVALID.",
+			args: arguments{
+				method: http.MethodGet,
+				target: "/v2/Repo/manifests/latest",
+			},
+			want: want{
+				status: http.StatusBadRequest,
+				codes:  []OCIErrorCode{OCINameInvalid},
+			},
+		},
+		"ReferenceInvalid": {
+			reason: "An invalid reference should be rejected with MANIFEST_INVALID.",
+			args: arguments{
+				method: http.MethodGet,
+				target: "/v2/repo/manifests/-bad",
+			},
+			want: want{
+				status: http.StatusBadRequest,
+				codes:  []OCIErrorCode{OCIManifestInvalid},
+			},
+		},
+		"ManifestInvalid": {
+			reason: "An invalid request body should be rejected with MANIFEST_INVALID.",
+			args: arguments{
+				method: http.MethodPut,
+				target: "/v2/repo/manifests/latest",
+				body:   `{"schemaVersion":"two"}`,
+			},
+			want: want{
+				status: http.StatusBadRequest,
+				codes:  []OCIErrorCode{OCIManifestInvalid},
+			},
+		},
+		"Unsupported": {
+			reason: "An unsupported method should be rejected with UNSUPPORTED.",
+			args: arguments{
+				method: http.MethodDelete,
+				target: "/v2/repo/manifests/latest",
+			},
+			want: want{
+				status: http.StatusMethodNotAllowed,
+				codes:  []OCIErrorCode{OCIUnsupported},
+			},
+		},
+		"Unauthorized": {
+			reason: "A failed security requirement should be rejected with UNAUTHORIZED.",
+			args: arguments{
+				method: http.MethodGet,
+				target: "/v2/repo/manifests/latest",
+				auth: func(context.Context, *openapi3filter.AuthenticationInput) error {
+					return errBoom
+				},
+			},
+			want: want{
+				status: http.StatusUnauthorized,
+				codes:  []OCIErrorCode{OCIUnauthorized},
+			},
+		},
+		"Denied": {
+			reason: "A security requirement failing with ErrDenied should be rejected with DENIED.",
+			args: arguments{
+				method: http.MethodGet,
+				target: "/v2/repo/manifests/latest",
+				auth: func(context.Context, *openapi3filter.AuthenticationInput) error {
+					return ErrDenied
+				},
+			},
+			want: want{
+				status: http.StatusForbidden,
+				codes:  []OCIErrorCode{OCIDenied},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			opts := &Options{Format: FormatOCI}
+			opts.Options.AuthenticationFunc = openapi3filter.NoopAuthenticationFunc
+			if tc.args.auth != nil {
+				opts.Options.AuthenticationFunc = tc.args.auth
+			}
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.args.method, tc.args.target, strings.NewReader(tc.args.body))
+			if tc.args.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			RequestValidatorWithOptions(swagger, opts)(next).ServeHTTP(rr, req)
+			res := rr.Result()
+			defer res.Body.Close()
+			if diff := cmp.Diff(tc.want.status, res.StatusCode); diff != "" {
+				t.Errorf("\n%s\nRequestValidatorWithOptions(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+			if tc.want.codes == nil {
+				return
+			}
+			body := &ociErrors{}
+			_ = json.NewDecoder(res.Body).Decode(body)
+			got := make([]OCIErrorCode, len(body.Errors))
+			for i, e := range body.Errors {
+				got[i] = e.Code
+			}
+			if diff := cmp.Diff(tc.want.codes, got); diff != "" {
+				t.Errorf("\n%s\nRequestValidatorWithOptions(...): -want codes, +got codes:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestOCIErrorsFromValidationError(t *testing.T) {
+	type want struct {
+		status int
+		codes  []OCIErrorCode
+	}
+	cases := map[string]struct {
+		reason string
+		err    error
+		want   want
+	}{
+		"TooLarge": {
+			reason: "A request body over the size limit should be rejected with SIZE_INVALID.",
+			err:    &openapi3filter.RequestError{Err: &http.MaxBytesError{Limit: 1}},
+			want:   want{status: http.StatusRequestEntityTooLarge, codes: []OCIErrorCode{OCISizeInvalid}},
+		},
+		"Internal": {
+			reason: "An error that is not a validation failure should be an internal server error.",
+			err:    errors.New("boom"),
+			want:   want{status: http.StatusInternalServerError, codes: []OCIErrorCode{}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			status, errs := OCIOptions{}.errorsFromValidationError(tc.err)
+			got := want{status: status, codes: make([]OCIErrorCode, len(errs))}
+			for i, e := range errs {
+				got.codes[i] = e.Code
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nerrorsFromValidationError(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal"
+	"github.com/upbound/build-submodule-demo/internal/client/auth"
+	"github.com/upbound/build-submodule-demo/internal/ratelimit"
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+// EntityAnonymous is the entity type of unauthenticated callers, which are
+// rate limited by client IP.
+const EntityAnonymous = "anonymous"
+
+const (
+	errRateLimited    = "rate limit exceeded"
+	errTakeToken      = "failed to take rate limit token, allowing request"
+	errParseRateLimit = "failed to parse rate limit for %q"
+)
+
+// Rate limit response headers.
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+)
+
+// RateLimits configure the limits applied to each caller. The most specific
+// limit wins, in order: entity type and route, route, entity type, default.
+// A limit with a burst of zero or less is not enforced.
+type RateLimits struct {
+	Default ratelimit.Limit
+
+	// Entities are keyed by entity type, e.g. user, robot or anonymous.
+	Entities map[string]ratelimit.Limit
+
+	// Routes are keyed by route pattern, optionally prefixed by an entity
+	// type, e.g. /v1/demo or robot:/v1/demo.
+	Routes map[string]ratelimit.Limit
+}
+
+// RateLimitsFromOptions parses the rate limits from the supplied options.
+func RateLimitsFromOptions(opts internal.RateLimitOptions) (RateLimits, error) {
+	def, err := ratelimit.ParseLimit(opts.RateLimitDefault)
+	if err != nil {
+		return RateLimits{}, errors.Wrapf(err, errParseRateLimit, "default")
+	}
+	rl := RateLimits{
+		Default:  def,
+		Entities: make(map[string]ratelimit.Limit, len(opts.RateLimitEntities)),
+		Routes:   make(map[string]ratelimit.Limit, len(opts.RateLimitRoutes)),
+	}
+	for k, v := range opts.RateLimitEntities {
+		if rl.Entities[k], err = ratelimit.ParseLimit(v); err != nil {
+			return RateLimits{}, errors.Wrapf(err, errParseRateLimit, k)
+		}
+	}
+	for k, v := range opts.RateLimitRoutes {
+		if rl.Routes[k], err = ratelimit.ParseLimit(v); err != nil {
+			return RateLimits{}, errors.Wrapf(err, errParseRateLimit, k)
+		}
+	}
+	return rl, nil
+}
+
+// limit returns the limit for the entity and route, and whether the limit is
+// specific to the route.
+func (rl RateLimits) limit(entity, route string) (ratelimit.Limit, bool) {
+	if l, ok := rl.Routes[entity+":"+route]; ok {
+		return l, true
+	}
+	if l, ok := rl.Routes[route]; ok {
+		return l, true
+	}
+	if l, ok := rl.Entities[entity]; ok {
+		return l, false
+	}
+	return rl.Default, false
+}
+
+// RateLimiter is token bucket rate limiting middleware keyed by the
+// authenticated principal, falling back to the client IP.
+type RateLimiter struct {
+	log    logging.Logger
+	store  ratelimit.Store
+	limits RateLimits
+}
+
+// RateLimiterOpt modifies rate limiting middleware.
+type RateLimiterOpt func(l *RateLimiter)
+
+// RateLimiterWithLogger sets the logger for rate limiting middleware.
+func RateLimiterWithLogger(log logging.Logger) RateLimiterOpt {
+	return func(l *RateLimiter) {
+		l.log = log
+	}
+}
+
+// NewRateLimiter constructs new rate limiting middleware.
+func NewRateLimiter(store ratelimit.Store, limits RateLimits, opts ...RateLimiterOpt) *RateLimiter {
+	l := &RateLimiter{
+		log:    logging.NewNopLogger(),
+		store:  store,
+		limits: limits,
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	return l
+}
+
+// Limit rejects requests from callers that have exceeded their limit with a
+// too many requests problem. Responses carry the RateLimit headers. It must
+// be used after authentication to limit by principal.
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entity, id := principal(r)
+		route := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+		lim, perRoute := l.limits.limit(entity, route)
+		if lim.Burst <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := entity + ":" + id
+		if perRoute {
+			key += ":" + route
+		}
+		res, err := l.store.Take(r.Context(), key, lim)
+		if err != nil {
+			l.log.Info(errTakeToken, "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set(HeaderRateLimitLimit, strconv.Itoa(lim.Burst))
+		w.Header().Set(HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+		w.Header().Set(HeaderRateLimitReset, seconds(res.Reset))
+		if !res.Allowed {
+			w.Header().Set("Retry-After", seconds(res.RetryAfter))
+			problem.Error(w, r, http.StatusTooManyRequests, errRateLimited)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// principal returns the entity type and ID of the caller.
+func principal(r *http.Request) (string, string) {
+	if id, ok := auth.UserIDFromContext(r.Context()); ok {
+		return string(auth.User), strconv.FormatUint(uint64(id), 10)
+	}
+	if id, ok := auth.RobotIDFromContext(r.Context()); ok {
+		return string(auth.Robot), id.String()
+	}
+	return EntityAnonymous, strings.ToLower(forwarded.FromRequest(r).ClientIP)
+}
+
+// seconds formats a duration as whole seconds, rounding up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/build-submodule-demo/internal/client/auth"
+	"github.com/upbound/build-submodule-demo/internal/ratelimit"
+)
+
+func TestRateLimiterLimit(t *testing.T) {
+	type arguments struct {
+		limits RateLimits
+		user   bool
+	}
+	type want struct {
+		status    int
+		limit     string
+		remaining string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Anonymous": {
+			reason: "Anonymous callers should be limited by the entity limit for their client IP.",
+			args: arguments{
+				limits: RateLimits{
+					Default:  ratelimit.Limit{Rate: 1, Burst: 5},
+					Entities: map[string]ratelimit.Limit{EntityAnonymous: {Rate: 1, Burst: 1}},
+				},
+			},
+			want: want{
+				status:    http.StatusTooManyRequests,
+				limit:     "1",
+				remaining: "0",
+			},
+		},
+		"User": {
+			reason: "Authenticated users should be limited by the default limit.",
+			args: arguments{
+				limits: RateLimits{
+					Default:  ratelimit.Limit{Rate: 1, Burst: 5},
+					Entities: map[string]ratelimit.Limit{EntityAnonymous: {Rate: 1, Burst: 1}},
+				},
+				user: true,
+			},
+			want: want{
+				status:    http.StatusOK,
+				limit:     "5",
+				remaining: "3",
+			},
+		},
+		"Unlimited": {
+			reason: "Requests should not be limited if no limit applies.",
+			args: arguments{
+				limits: RateLimits{},
+			},
+			want: want{
+				status: http.StatusOK,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h := NewRateLimiter(ratelimit.NewMemoryStore(), tc.args.limits).Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			var rr *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				ctx := context.Background()
+				if tc.args.user {
+					ctx = context.WithValue(ctx, auth.UserKey, uint(1))
+				}
+				req, _ := http.NewRequestWithContext(ctx, "GET", "/v1/demo", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				rr = httptest.NewRecorder()
+				h.ServeHTTP(rr, req)
+			}
+			if diff := cmp.Diff(tc.want.status, rr.Code); diff != "" {
+				t.Errorf("\n%s\nLimit(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.limit, rr.Header().Get(HeaderRateLimitLimit)); diff != "" {
+				t.Errorf("\n%s\nLimit(...): -want limit, +got limit:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.remaining, rr.Header().Get(HeaderRateLimitRemaining)); diff != "" {
+				t.Errorf("\n%s\nLimit(...): -want remaining, +got remaining:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	chimid "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/upbound/build-submodule-demo/internal/log"
+	"github.com/upbound/build-submodule-demo/internal/reporting"
+	"github.com/upbound/build-submodule-demo/internal/server/metrics/otel"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errPanic       = "Recovered panic while handling request"
+	errReport      = "failed to report recovered panic"
+	reportTimeout  = 10 * time.Second
+	msgServerError = "internal server error"
+)
+
+// Recoverer is panic recovery middleware.
+type Recoverer struct {
+	log      logging.Logger
+	reporter reporting.Reporter
+	redact   []string
+}
+
+// RecovererOpt modifies panic recovery middleware.
+type RecovererOpt func(r *Recoverer)
+
+// RecovererWithLogger sets the logger for panic recovery middleware. The
+// request's context logger is preferred if present.
+func RecovererWithLogger(l logging.Logger) RecovererOpt {
+	return func(r *Recoverer) {
+		r.log = l
+	}
+}
+
+// RecovererWithReporter sets the reporter that recovered panics are
+// forwarded to.
+func RecovererWithReporter(rp reporting.Reporter) RecovererOpt {
+	return func(r *Recoverer) {
+		r.reporter = rp
+	}
+}
+
+// RecovererWithRedactParams sets the query parameters whose values are
+// redacted from the request URI of reported panics.
+func RecovererWithRedactParams(params ...string) RecovererOpt {
+	return func(r *Recoverer) {
+		r.redact = params
+	}
+}
+
+// NewRecoverer constructs new panic recovery middleware.
+func NewRecoverer(opts ...RecovererOpt) *Recoverer {
+	r := &Recoverer{
+		log:      logging.NewNopLogger(),
+		reporter: reporting.NopReporter{},
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Recover recovers from panics in subsequent handlers. The panic and its stack
+// are logged, counted and reported, and a 500 problem carrying the request ID
+// is returned to the caller.
+func (rc *Recoverer) Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			// The server aborts the response without logging for this value,
+			// so we must not swallow it.
+			if v == http.ErrAbortHandler { //nolint:errorlint,goerr113 // sentinel is compared by identity.
+				panic(v)
+			}
+			stack := debug.Stack()
+			id := chimid.GetReqID(r.Context())
+			l, ok := log.FromContext(r.Context())
+			if !ok {
+				l = rc.log
+			}
+			// The runtime logger has no error level, so panics are logged at
+			// the highest level available.
+			l.Info(errPanic, "panic", v, "stack", string(stack))
+			otel.PanicRecovered(r.Context(), r)
+
+			e := reporting.Event{
+				Time:      time.Now(),
+				RequestID: id,
+				Method:    r.Method,
+				URI:       log.RedactURI(r.RequestURI, rc.redact),
+				Message:   fmt.Sprint(v),
+				Stack:     string(stack),
+			}
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+				defer cancel()
+				if err := rc.reporter.Report(ctx, e); err != nil {
+					l.Info(errReport, "error", err)
+				}
+			}()
+
+			problem.Error(w, r, http.StatusInternalServerError, msgServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
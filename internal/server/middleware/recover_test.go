@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	chimid "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/build-submodule-demo/internal/reporting"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+func TestRecover(t *testing.T) {
+	type arguments struct {
+		next http.Handler
+	}
+	type want struct {
+		status   int
+		body     *problem.Problem
+		reported bool
+		uri      string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"NoPanic": {
+			reason: "If the next handler does not panic its response should be returned.",
+			args: arguments{
+				next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			},
+			want: want{
+				status: http.StatusOK,
+			},
+		},
+		"Panic": {
+			reason: "If the next handler panics a 500 with the request ID should be returned and the panic reported.",
+			args: arguments{
+				next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					panic("boom")
+				}),
+			},
+			want: want{
+				status: http.StatusInternalServerError,
+				body: &problem.Problem{
+					Type:      problem.TypeDefault,
+					Title:     http.StatusText(http.StatusInternalServerError),
+					Status:    http.StatusInternalServerError,
+					Detail:    msgServerError,
+					Instance:  "/doesnt/matter",
+					RequestID: "abc",
+				},
+				reported: true,
+				uri:      "/doesnt/matter?page=2&token=REDACTED",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reported := make(chan reporting.Event, 1)
+			rc := NewRecoverer(
+				RecovererWithReporter(&reporting.MockReporter{
+					ReportFn: func(_ context.Context, e reporting.Event) error {
+						reported <- e
+						return nil
+					},
+				}),
+				RecovererWithRedactParams("token"),
+			)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/doesnt/matter?page=2&token=s3cr3t", nil)
+			req = req.WithContext(context.WithValue(req.Context(), chimid.RequestIDKey, "abc"))
+			rc.Recover(tc.args.next).ServeHTTP(rr, req)
+			res := rr.Result()
+			defer res.Body.Close()
+			if diff := cmp.Diff(tc.want.status, res.StatusCode); diff != "" {
+				t.Errorf("\n%s\nRecover(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+			if tc.want.body != nil {
+				got := &problem.Problem{}
+				_ = json.NewDecoder(res.Body).Decode(got)
+				if diff := cmp.Diff(tc.want.body, got); diff != "" {
+					t.Errorf("\n%s\nRecover(...): -want body, +got body:\n%s", tc.reason, diff)
+				}
+			}
+			if !tc.want.reported {
+				// Reports are only sent after a panic is recovered, so none
+				// can arrive once the handler has returned without one.
+				select {
+				case e := <-reported:
+					t.Errorf("\n%s\nRecover(...): unexpected report: %v", tc.reason, e)
+				default:
+				}
+				return
+			}
+			select {
+			case e := <-reported:
+				if diff := cmp.Diff("boom", e.Message); diff != "" {
+					t.Errorf("\n%s\nRecover(...): -want message, +got message:\n%s", tc.reason, diff)
+				}
+				if diff := cmp.Diff(tc.want.uri, e.URI); diff != "" {
+					t.Errorf("\n%s\nRecover(...): -want URI, +got URI:\n%s", tc.reason, diff)
+				}
+			case <-time.After(time.Second):
+				t.Errorf("\n%s\nRecover(...): expected panic to be reported", tc.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	chimid "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+
+	shttp "github.com/upbound/build-submodule-demo/internal/client/http"
+	"github.com/upbound/build-submodule-demo/internal/log"
+)
+
+// validRequestID restricts accepted request IDs to values that are safe to
+// log and forward.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID returns middleware that assigns an ID to every request. The ID is
+// taken from the supplied trusted header if present and valid, otherwise it is
+// generated. An empty header means incoming IDs are never trusted. The ID is
+// stored in the request context, echoed in the X-Request-ID response header
+// and attached to the context logger.
+func RequestID(header string, l logging.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := ""
+			if header != "" {
+				id = r.Header.Get(header)
+			}
+			if !validRequestID.MatchString(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(shttp.RequestIDHeader, id)
+			ctx := context.WithValue(r.Context(), chimid.RequestIDKey, id)
+			ctx = log.NewContext(ctx, l.WithValues("requestID", id))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	chimid "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/go-cmp/cmp"
+
+	shttp "github.com/upbound/build-submodule-demo/internal/client/http"
+)
+
+func TestRequestID(t *testing.T) {
+	type arguments struct {
+		header   string
+		incoming string
+	}
+	type want struct {
+		trusted bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Trusted": {
+			reason: "A valid ID in the trusted header should be used.",
+			args: arguments{
+				header:   shttp.RequestIDHeader,
+				incoming: "abc-123",
+			},
+			want: want{
+				trusted: true,
+			},
+		},
+		"Untrusted": {
+			reason: "An incoming ID should be ignored if no header is trusted.",
+			args: arguments{
+				incoming: "abc-123",
+			},
+			want: want{
+				trusted: false,
+			},
+		},
+		"Invalid": {
+			reason: "An invalid ID in the trusted header should be replaced.",
+			args: arguments{
+				header:   shttp.RequestIDHeader,
+				incoming: "abc 123\n",
+			},
+			want: want{
+				trusted: false,
+			},
+		},
+		"Missing": {
+			reason: "An ID should be generated if none is supplied.",
+			args: arguments{
+				header: shttp.RequestIDHeader,
+			},
+			want: want{
+				trusted: false,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = chimid.GetReqID(r.Context())
+			})
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(context.Background(), "GET", "doesnt/matter", nil)
+			req.Header.Set(shttp.RequestIDHeader, tc.args.incoming)
+			RequestID(tc.args.header, logging.NewNopLogger())(next).ServeHTTP(rr, req)
+			if got == "" {
+				t.Errorf("\n%s\nRequestID(...): expected request ID in context", tc.reason)
+			}
+			if diff := cmp.Diff(tc.want.trusted, got == tc.args.incoming); diff != "" {
+				t.Errorf("\n%s\nRequestID(...): -want trusted, +got trusted:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(got, rr.Header().Get(shttp.RequestIDHeader)); diff != "" {
+				t.Errorf("\n%s\nRequestID(...): -want header, +got header:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
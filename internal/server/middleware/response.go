@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"github.com/upbound/build-submodule-demo/internal/server/metrics/otel"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+// Response validation modes.
+const (
+	// ResponseValidationAuto logs violations in dev mode and is otherwise off.
+	ResponseValidationAuto = "auto"
+	// ResponseValidationOff disables response validation.
+	ResponseValidationOff = "off"
+	// ResponseValidationLog logs violations and returns the response as is.
+	ResponseValidationLog = "log"
+	// ResponseValidationFail replaces violating responses with a 500 problem.
+	ResponseValidationFail = "fail"
+)
+
+const (
+	errResponseInvalid = "Response does not match API specification"
+	msgResponseInvalid = "response does not match the API specification"
+)
+
+// ResponseValidationMode resolves the effective response validation mode.
+func ResponseValidationMode(mode string, devMode bool) string {
+	if mode != ResponseValidationAuto {
+		return mode
+	}
+	if devMode {
+		return ResponseValidationLog
+	}
+	return ResponseValidationOff
+}
+
+// ResponseValidatorWithOptions creates middleware that validates the status,
+// headers and body of responses against the supplied spec. Responses are
+// buffered, so it is intended for development rather than production use.
+// Violations are counted and, depending on the mode, logged or turned into a
+// 500 problem.
+func ResponseValidatorWithOptions(swagger *openapi3.T, mode string, l logging.Logger, options *Options) func(next http.Handler) http.Handler {
+	if mode == ResponseValidationOff || mode == ResponseValidationAuto || mode == "" {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	router, err := gorillamux.NewRouter(swagger)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			bw := &bufferedResponseWriter{header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(bw, r)
+
+			if err := validateResponse(r, route, pathParams, bw, options); err != nil {
+				otel.ResponseValidationFailed(r.Context(), r, route.Path)
+				l.Info(errResponseInvalid, "path", route.Path, "method", r.Method, "status", bw.status, "error", err)
+				if mode == ResponseValidationFail {
+					problem.Error(w, r, http.StatusInternalServerError, msgResponseInvalid)
+					return
+				}
+			}
+			bw.flush(w)
+		})
+	}
+}
+
+func validateResponse(r *http.Request, route *routers.Route, pathParams map[string]string, bw *bufferedResponseWriter, options *Options) error {
+	fopts := &openapi3filter.Options{}
+	if options != nil {
+		*fopts = options.Options
+	}
+	fopts.IncludeResponseStatus = true
+	input := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    fopts,
+		},
+		Status:  bw.status,
+		Header:  bw.header,
+		Options: fopts,
+	}
+	input.SetBodyBytes(bw.body.Bytes())
+	if err := openapi3filter.ValidateResponse(r.Context(), input); err != nil {
+		return err
+	}
+	return validateResponseHeaders(route, bw)
+}
+
+// validateResponseHeaders verifies that headers the spec declares as required
+// are present, which kin-openapi does not check.
+func validateResponseHeaders(route *routers.Route, bw *bufferedResponseWriter) error {
+	ref := route.Operation.Responses.Get(bw.status)
+	if ref == nil {
+		ref = route.Operation.Responses.Default()
+	}
+	if ref == nil || ref.Value == nil {
+		return nil
+	}
+	for name, h := range ref.Value.Headers {
+		if h == nil || h.Value == nil || !h.Value.Required {
+			continue
+		}
+		if bw.header.Get(name) == "" {
+			return fmt.Errorf("response header %q is required", name)
+		}
+	}
+	return nil
+}
+
+// bufferedResponseWriter holds a response until it has been validated.
+type bufferedResponseWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponseWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponseWriter) WriteHeader(status int) {
+	if b.wroteHeader {
+		return
+	}
+	b.wroteHeader = true
+	b.status = status
+}
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	if b.header.Get("Content-Type") == "" {
+		b.header.Set("Content-Type", http.DetectContentType(p))
+	}
+	return b.body.Write(p)
+}
+
+// flush writes the buffered response to the supplied writer.
+func (b *bufferedResponseWriter) flush(w http.ResponseWriter) {
+	for k, v := range b.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(b.status)
+	_, _ = w.Write(b.body.Bytes())
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/go-cmp/cmp"
+)
+
+const thingsSpec = `
+openapi: 3.0.0
+info:
+  title: Things
+  version: '1.0'
+paths:
+  /things:
+    get:
+      responses:
+        '200':
+          description: OK
+          headers:
+            X-Total:
+              required: true
+              schema:
+                type: integer
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [name]
+                properties:
+                  name:
+                    type: string
+`
+
+func TestResponseValidatorWithOptions(t *testing.T) {
+	swagger, err := openapi3.NewLoader().LoadFromData([]byte(thingsSpec))
+	if err != nil {
+		t.Fatalf("LoadFromData(...): unexpected error: %v", err)
+	}
+	respond := func(status int, total, body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if total != "" {
+				w.Header().Set("X-Total", total)
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		})
+	}
+	type arguments struct {
+		mode string
+		next http.Handler
+	}
+	type want struct {
+		status int
+		body   string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Valid": {
+			reason: "A valid response should be returned unchanged.",
+			args: arguments{
+				mode: ResponseValidationFail,
+				next: respond(http.StatusOK, "1", `{"name":"a"}`),
+			},
+			want: want{
+				status: http.StatusOK,
+				body:   `{"name":"a"}`,
+			},
+		},
+		"InvalidBodyLog": {
+			reason: "An invalid response should be returned unchanged in log mode.",
+			args: arguments{
+				mode: ResponseValidationLog,
+				next: respond(http.StatusOK, "1", `{"size":1}`),
+			},
+			want: want{
+				status: http.StatusOK,
+				body:   `{"size":1}`,
+			},
+		},
+		"InvalidBodyFail": {
+			reason: "A response with an invalid body should be replaced in fail mode.",
+			args: arguments{
+				mode: ResponseValidationFail,
+				next: respond(http.StatusOK, "1", `{"size":1}`),
+			},
+			want: want{
+				status: http.StatusInternalServerError,
+			},
+		},
+		"MissingHeaderFail": {
+			reason: "A response missing a required header should be replaced in fail mode.",
+			args: arguments{
+				mode: ResponseValidationFail,
+				next: respond(http.StatusOK, "", `{"name":"a"}`),
+			},
+			want: want{
+				status: http.StatusInternalServerError,
+			},
+		},
+		"UndocumentedStatusFail": {
+			reason: "A response with an undocumented status should be replaced in fail mode.",
+			args: arguments{
+				mode: ResponseValidationFail,
+				next: respond(http.StatusTeapot, "1", `{"name":"a"}`),
+			},
+			want: want{
+				status: http.StatusInternalServerError,
+			},
+		},
+		"Off": {
+			reason: "Responses should not be validated when validation is off.",
+			args: arguments{
+				mode: ResponseValidationOff,
+				next: respond(http.StatusTeapot, "", `{}`),
+			},
+			want: want{
+				status: http.StatusTeapot,
+				body:   `{}`,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			ResponseValidatorWithOptions(swagger, tc.args.mode, logging.NewNopLogger(), nil)(tc.args.next).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/things", nil))
+			if diff := cmp.Diff(tc.want.status, rr.Code); diff != "" {
+				t.Errorf("\n%s\nResponseValidatorWithOptions(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+			if tc.want.body == "" {
+				return
+			}
+			if diff := cmp.Diff(tc.want.body, rr.Body.String()); diff != "" {
+				t.Errorf("\n%s\nResponseValidatorWithOptions(...): -want body, +got body:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+)
+
+const (
+	errRewriteJSON = "failed to rewrite JSON response, returning it unmodified"
+)
+
+// rewriteHeaders are the response headers holding a single URL.
+var rewriteHeaders = []string{"Location", "Content-Location"}
+
+// Rewriter is middleware that rewrites URLs in responses to use the
+// client-facing scheme, host and path prefix of the request, as determined by
+// Forwarded. Absolute-path references and absolute URLs for the host the
+// request was received on are rewritten; URLs for other hosts are left as is.
+type Rewriter struct {
+	log    logging.Logger
+	prefix string
+	fields [][]string
+}
+
+// RewriterOpt modifies rewriting middleware.
+type RewriterOpt func(rw *Rewriter)
+
+// RewriterWithLogger sets the logger for rewriting middleware.
+func RewriterWithLogger(l logging.Logger) RewriterOpt {
+	return func(rw *Rewriter) {
+		rw.log = l
+	}
+}
+
+// RewriterWithPathPrefix sets the path prefix used when a trusted proxy does
+// not supply one with X-Forwarded-Prefix.
+func RewriterWithPathPrefix(p string) RewriterOpt {
+	return func(rw *Rewriter) {
+		rw.prefix = strings.TrimSuffix(p, "/")
+	}
+}
+
+// RewriterWithJSONFields sets the fields of JSON responses holding URLs, as
+// dot separated paths, e.g. next or links.self. Arrays are traversed, so
+// items.url rewrites the url field of every element of items.
+func RewriterWithJSONFields(fields ...string) RewriterOpt {
+	return func(rw *Rewriter) {
+		rw.fields = make([][]string, 0, len(fields))
+		for _, f := range fields {
+			if f != "" {
+				rw.fields = append(rw.fields, strings.Split(f, "."))
+			}
+		}
+	}
+}
+
+// NewRewriter constructs new rewriting middleware.
+func NewRewriter(opts ...RewriterOpt) *Rewriter {
+	rw := &Rewriter{
+		log: logging.NewNopLogger(),
+	}
+	for _, o := range opts {
+		o(rw)
+	}
+	return rw
+}
+
+// Rewrite rewrites the Location, Content-Location and Link headers and the
+// configured JSON fields of responses. JSON responses are buffered if any
+// fields are configured.
+func (rw *Rewriter) Rewrite(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := &rewriteWriter{ResponseWriter: w, rw: rw, u: rw.urlRewriter(r)}
+		next.ServeHTTP(ww, r)
+		ww.finish()
+	})
+}
+
+// urlRewriter returns a function that rewrites a URL for the request.
+func (rw *Rewriter) urlRewriter(r *http.Request) func(string) string {
+	fwd := forwarded.FromRequest(r)
+	prefix := fwd.Prefix
+	if prefix == "" {
+		prefix = rw.prefix
+	}
+	return func(s string) string {
+		u, err := url.Parse(s)
+		if err != nil {
+			return s
+		}
+		switch {
+		case u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/"):
+		case u.Host != "" && strings.EqualFold(u.Host, r.Host):
+		default:
+			return s
+		}
+		u.Scheme = fwd.Scheme
+		u.Host = fwd.Host
+		if prefix != "" && !strings.HasPrefix(u.Path, prefix+"/") && u.Path != prefix {
+			u.Path = prefix + u.Path
+			if u.RawPath != "" {
+				u.RawPath = prefix + u.RawPath
+			}
+		}
+		return u.String()
+	}
+}
+
+// rewriteLinks rewrites the URLs of an RFC 8288 Link header value.
+func rewriteLinks(v string, rewrite func(string) string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(v, '<')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(v[start:], '>')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(v[:start+1])
+		b.WriteString(rewrite(v[start+1 : end]))
+		v = v[end:]
+		// Skip to the next link so that angle brackets in parameters are
+		// not mistaken for URLs.
+		next := nextLink(v)
+		b.WriteString(v[:next])
+		v = v[next:]
+	}
+	b.WriteString(v)
+	return b.String()
+}
+
+// nextLink returns the index of the comma separating the link at the start of
+// v from the next one, or the length of v.
+func nextLink(v string) int {
+	quoted := false
+	for i, c := range v {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			return i
+		}
+	}
+	return len(v)
+}
+
+// rewriteJSON rewrites the URL string values at the supplied path.
+func rewriteJSON(v any, path []string, rewrite func(string) string) (any, bool) {
+	switch t := v.(type) {
+	case []any:
+		changed := false
+		for i := range t {
+			var c bool
+			t[i], c = rewriteJSON(t[i], path, rewrite)
+			changed = changed || c
+		}
+		return t, changed
+	case map[string]any:
+		if len(path) == 0 {
+			return t, false
+		}
+		child, ok := t[path[0]]
+		if !ok {
+			return t, false
+		}
+		nv, changed := rewriteJSON(child, path[1:], rewrite)
+		t[path[0]] = nv
+		return t, changed
+	case string:
+		if len(path) != 0 {
+			return t, false
+		}
+		nv := rewrite(t)
+		return nv, nv != t
+	}
+	return v, false
+}
+
+// rewriteWriter rewrites headers when the response status is written and
+// buffers JSON bodies with fields to rewrite.
+type rewriteWriter struct {
+	http.ResponseWriter
+	rw *Rewriter
+	u  func(string) string
+
+	wroteHeader bool
+	buffer      bool
+	status      int
+	body        bytes.Buffer
+}
+
+func (w *rewriteWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.Header()
+	for _, k := range rewriteHeaders {
+		if v := h.Get(k); v != "" {
+			h.Set(k, w.u(v))
+		}
+	}
+	if links := h.Values("Link"); len(links) > 0 {
+		rewritten := make([]string, len(links))
+		for i, l := range links {
+			rewritten[i] = rewriteLinks(l, w.u)
+		}
+		h["Link"] = rewritten
+	}
+	if len(w.rw.fields) > 0 && h.Get("Content-Encoding") == "" && isJSON(h.Get("Content-Type")) {
+		w.buffer = true
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *rewriteWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.buffer {
+		return w.body.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush flushes the response unless it is buffered.
+func (w *rewriteWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.buffer {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer.
+func (w *rewriteWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish writes a buffered body with the configured fields rewritten.
+func (w *rewriteWriter) finish() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.buffer {
+		return
+	}
+	body := w.body.Bytes()
+	if b, err := w.rewriteBody(body); err != nil {
+		w.rw.log.Debug(errRewriteJSON, "error", err)
+	} else {
+		body = b
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+func (w *rewriteWriter) rewriteBody(body []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	changed := false
+	for _, f := range w.rw.fields {
+		var c bool
+		v, c = rewriteJSON(v, f, w.u)
+		changed = changed || c
+	}
+	if !changed {
+		return body, nil
+	}
+	var buf bytes.Buffer
+	e := json.NewEncoder(&buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isJSON reports whether a media type is JSON, including structured syntax
+// suffixes such as application/problem+json.
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+)
+
+func TestRewriterRewrite(t *testing.T) {
+	type arguments struct {
+		prefix   string
+		location string
+		link     string
+		body     string
+	}
+	type want struct {
+		location string
+		link     string
+		body     string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Location": {
+			reason: "An absolute-path Location should use the client-facing origin and prefix.",
+			args: arguments{
+				location: "/v1/demo/1",
+			},
+			want: want{
+				location: "https://api.example.com/demo/v1/demo/1",
+			},
+		},
+		"LocationInternalHost": {
+			reason: "An absolute Location for the internal host should use the client-facing origin.",
+			args: arguments{
+				location: "http://api.internal:8081/v1/demo/1?x=y",
+			},
+			want: want{
+				location: "https://api.example.com/demo/v1/demo/1?x=y",
+			},
+		},
+		"LocationOtherHost": {
+			reason: "A Location for another host should not be rewritten.",
+			args: arguments{
+				location: "https://auth.example.com/login",
+			},
+			want: want{
+				location: "https://auth.example.com/login",
+			},
+		},
+		"Link": {
+			reason: "Every URL in a Link header should be rewritten.",
+			args: arguments{
+				link: `</v1/demo?page=2>; rel="next", </v1/demo?page=9>; rel="last"; title="a>b"`,
+			},
+			want: want{
+				link: `<https://api.example.com/demo/v1/demo?page=2>; rel="next", <https://api.example.com/demo/v1/demo?page=9>; rel="last"; title="a>b"`,
+			},
+		},
+		"JSON": {
+			reason: "Configured JSON fields should be rewritten, including within arrays.",
+			args: arguments{
+				body: `{"next":"/v1/demo?page=2","items":[{"url":"/v1/demo/1","n":1.50}]}`,
+			},
+			want: want{
+				body: `{"items":[{"n":1.50,"url":"https://api.example.com/demo/v1/demo/1"}],"next":"https://api.example.com/demo/v1/demo?page=2"}` + "\n",
+			},
+		},
+		"JSONUnchanged": {
+			reason: "JSON without URLs to rewrite should be returned as is.",
+			args: arguments{
+				body: `{"next":"https://other.example.com/x"}`,
+			},
+			want: want{
+				body: `{"next":"https://other.example.com/x"}`,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rw := NewRewriter(RewriterWithPathPrefix("/demo/"), RewriterWithJSONFields("next", "items.url"))
+			h := rw.Rewrite(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.args.location != "" {
+					w.Header().Set("Location", tc.args.location)
+				}
+				if tc.args.link != "" {
+					w.Header().Set("Link", tc.args.link)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tc.args.body))
+			}))
+			req, _ := http.NewRequestWithContext(context.Background(), "GET", "http://api.internal:8081/v1/demo", nil)
+			req = req.WithContext(forwarded.NewContext(req.Context(), forwarded.Info{Scheme: "https", Host: "api.example.com"}))
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			got := want{
+				location: rr.Header().Get("Location"),
+				link:     rr.Header().Get("Link"),
+				body:     rr.Body.String(),
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nRewrite(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal/client/auth"
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+)
+
+const (
+	errParseSecurityHeaders = "failed to parse security headers of operation %s %s"
+)
+
+// ExtensionSecurityHeaders is an OpenAPI operation extension overriding the
+// security headers of an operation. It is an object of header names to
+// values; an empty value removes the header.
+const ExtensionSecurityHeaders = "x-security-headers"
+
+// Security response headers.
+const (
+	HeaderStrictTransportSecurity = "Strict-Transport-Security"
+	HeaderContentTypeOptions      = "X-Content-Type-Options"
+	HeaderReferrerPolicy          = "Referrer-Policy"
+	HeaderFrameOptions            = "X-Frame-Options"
+	HeaderContentSecurityPolicy   = "Content-Security-Policy"
+	HeaderCacheControl            = "Cache-Control"
+)
+
+// headerAuthorization identifies authenticated requests, whose responses must
+// not be stored.
+const headerAuthorization = "Authorization"
+
+// SecurityHeaders is middleware that sets hardening headers on responses.
+// HSTS is only sent to clients that connected over https, directly or through
+// a trusted proxy, and Cache-Control: no-store only on responses to
+// authenticated requests. Handlers may replace any header, and operations may
+// override them with the x-security-headers OpenAPI extension.
+type SecurityHeaders struct {
+	hstsMaxAge     time.Duration
+	hstsSubdomains bool
+	hstsPreload    bool
+	hsts           string
+
+	headers http.Header
+	noStore bool
+
+	router routers.Router
+	routes map[*openapi3.Operation]map[string]string
+}
+
+// SecurityHeadersOpt modifies security headers middleware.
+type SecurityHeadersOpt func(s *SecurityHeaders)
+
+// SecurityHeadersWithHSTS sets the max age of HSTS, and whether it includes
+// subdomains and allows preloading. A max age of 0 disables HSTS.
+func SecurityHeadersWithHSTS(maxAge time.Duration, subdomains, preload bool) SecurityHeadersOpt {
+	return func(s *SecurityHeaders) {
+		s.hstsMaxAge = maxAge
+		s.hstsSubdomains = subdomains
+		s.hstsPreload = preload
+	}
+}
+
+// SecurityHeadersWithReferrerPolicy sets the Referrer-Policy. An empty policy
+// omits the header.
+func SecurityHeadersWithReferrerPolicy(p string) SecurityHeadersOpt {
+	return func(s *SecurityHeaders) {
+		setOrDel(s.headers, HeaderReferrerPolicy, p)
+	}
+}
+
+// SecurityHeadersWithFrameOptions sets the X-Frame-Options, e.g. DENY or
+// SAMEORIGIN. Empty options omit the header.
+func SecurityHeadersWithFrameOptions(o string) SecurityHeadersOpt {
+	return func(s *SecurityHeaders) {
+		setOrDel(s.headers, HeaderFrameOptions, o)
+	}
+}
+
+// SecurityHeadersWithContentSecurityPolicy sets the Content-Security-Policy,
+// e.g. frame-ancestors 'none'. An empty policy omits the header.
+func SecurityHeadersWithContentSecurityPolicy(p string) SecurityHeadersOpt {
+	return func(s *SecurityHeaders) {
+		setOrDel(s.headers, HeaderContentSecurityPolicy, p)
+	}
+}
+
+// SecurityHeadersWithNoStore sets whether responses to authenticated requests
+// are marked as not to be stored by caches.
+func SecurityHeadersWithNoStore(b bool) SecurityHeadersOpt {
+	return func(s *SecurityHeaders) {
+		s.noStore = b
+	}
+}
+
+// NewSecurityHeaders constructs new security headers middleware. Overrides
+// are read from the operations of the supplied spec, which may be nil.
+func NewSecurityHeaders(swagger *openapi3.T, opts ...SecurityHeadersOpt) (*SecurityHeaders, error) {
+	s := &SecurityHeaders{
+		headers: http.Header{
+			HeaderContentTypeOptions:    []string{"nosniff"},
+			HeaderReferrerPolicy:        []string{"no-referrer"},
+			HeaderFrameOptions:          []string{"DENY"},
+			HeaderContentSecurityPolicy: []string{"frame-ancestors 'none'"},
+		},
+		noStore:        true,
+		hstsMaxAge:     365 * 24 * time.Hour,
+		hstsSubdomains: true,
+		routes:         map[*openapi3.Operation]map[string]string{},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if s.hstsMaxAge > 0 {
+		s.hsts = fmt.Sprintf("max-age=%d", int64(s.hstsMaxAge.Seconds()))
+		if s.hstsSubdomains {
+			s.hsts += "; includeSubDomains"
+		}
+		if s.hstsPreload {
+			s.hsts += "; preload"
+		}
+	}
+	if swagger == nil {
+		return s, nil
+	}
+	for path, item := range swagger.Paths {
+		for method, op := range item.Operations() {
+			v, ok := op.Extensions[ExtensionSecurityHeaders]
+			if !ok {
+				continue
+			}
+			h := map[string]string{}
+			if err := decodeExtension(v, &h); err != nil {
+				return nil, errors.Wrapf(err, errParseSecurityHeaders, method, path)
+			}
+			s.routes[op] = h
+		}
+	}
+	if len(s.routes) == 0 {
+		return s, nil
+	}
+	router, err := gorillamux.NewRouter(swagger)
+	if err != nil {
+		return nil, errors.Wrap(err, errBuildRouter)
+	}
+	s.router = router
+	return s, nil
+}
+
+// Handler sets the security headers of responses before they are handled.
+func (s *SecurityHeaders) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		for k, v := range s.headers {
+			h[k] = append([]string(nil), v...)
+		}
+		if s.hsts != "" && forwarded.FromRequest(r).Scheme == "https" {
+			h.Set(HeaderStrictTransportSecurity, s.hsts)
+		}
+		if s.noStore && authenticated(r) {
+			h.Set(HeaderCacheControl, "no-store")
+		}
+		for k, v := range s.overrides(r) {
+			setOrDel(h, k, v)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// overrides returns the security headers of the operation matching a
+// request, if any.
+func (s *SecurityHeaders) overrides(r *http.Request) map[string]string {
+	if s.router == nil {
+		return nil
+	}
+	route, _, err := s.router.FindRoute(r)
+	if err != nil {
+		return nil
+	}
+	return s.routes[route.Operation]
+}
+
+// authenticated reports whether a request carries credentials, or has been
+// authenticated.
+func authenticated(r *http.Request) bool {
+	if _, ok := auth.UserIDFromContext(r.Context()); ok {
+		return true
+	}
+	if _, ok := auth.RobotIDFromContext(r.Context()); ok {
+		return true
+	}
+	if strings.TrimSpace(r.Header.Get(headerAuthorization)) != "" {
+		return true
+	}
+	_, err := r.Cookie(auth.SessionCookieName)
+	return err == nil
+}
+
+// setOrDel sets a header, or deletes it if the value is empty.
+func setOrDel(h http.Header, k, v string) {
+	if v == "" {
+		h.Del(k)
+		return
+	}
+	h.Set(k, v)
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+)
+
+func TestSecurityHeadersHandler(t *testing.T) {
+	spec := &openapi3.T{
+		OpenAPI: "3.0.0",
+		Info:    &openapi3.Info{Title: "security", Version: "1"},
+		Paths: openapi3.Paths{
+			"/embed": &openapi3.PathItem{Get: &openapi3.Operation{
+				ExtensionProps: openapi3.ExtensionProps{Extensions: map[string]interface{}{
+					ExtensionSecurityHeaders: json.RawMessage(`{"X-Frame-Options":"","Content-Security-Policy":"frame-ancestors https://console.example.com"}`),
+				}},
+				Responses: openapi3.NewResponses(),
+			}},
+		},
+	}
+	defaults := map[string]string{
+		HeaderContentTypeOptions:    "nosniff",
+		HeaderReferrerPolicy:        "no-referrer",
+		HeaderFrameOptions:          "DENY",
+		HeaderContentSecurityPolicy: "frame-ancestors 'none'",
+	}
+	with := func(kv ...string) map[string]string {
+		h := map[string]string{}
+		for k, v := range defaults {
+			h[k] = v
+		}
+		for i := 0; i < len(kv); i += 2 {
+			if kv[i+1] == "" {
+				delete(h, kv[i])
+				continue
+			}
+			h[kv[i]] = kv[i+1]
+		}
+		return h
+	}
+	hsts := "max-age=3600; includeSubDomains"
+
+	cases := map[string]struct {
+		reason string
+		req    func() *http.Request
+		want   map[string]string
+	}{
+		"Defaults": {
+			reason: "Plain http responses should have the default headers without HSTS.",
+			req:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/", nil) },
+			want:   defaults,
+		},
+		"TLS": {
+			reason: "Responses over TLS should have HSTS.",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.TLS = &tls.ConnectionState{}
+				return r
+			},
+			want: with(HeaderStrictTransportSecurity, hsts),
+		},
+		"ForwardedHTTPS": {
+			reason: "Responses to https requests from trusted proxies should have HSTS.",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				return r.WithContext(forwarded.NewContext(r.Context(), forwarded.Info{Scheme: "https"}))
+			},
+			want: with(HeaderStrictTransportSecurity, hsts),
+		},
+		"Authenticated": {
+			reason: "Responses to authenticated requests should not be stored.",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("Authorization", "Bearer token")
+				return r
+			},
+			want: with(HeaderCacheControl, "no-store"),
+		},
+		"RouteOverride": {
+			reason: "Operations should be able to override and remove headers.",
+			req:    func() *http.Request { return httptest.NewRequest(http.MethodGet, "/embed", nil) },
+			want:   with(HeaderFrameOptions, "", HeaderContentSecurityPolicy, "frame-ancestors https://console.example.com"),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewSecurityHeaders(spec, SecurityHeadersWithHSTS(time.Hour, true, false))
+			if err != nil {
+				t.Fatalf("NewSecurityHeaders(...): %v", err)
+			}
+			rr := httptest.NewRecorder()
+			s.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, tc.req())
+			got := map[string]string{}
+			for k := range rr.Header() {
+				got[k] = rr.Header().Get(k)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nHandler(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/upbound/build-submodule-demo/internal/generics"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errCapacityExceeded = "server capacity exceeded"
+	errBacklogTimeout   = "timed out while waiting for a pending request to complete"
+	errBacklogCanceled  = "request was canceled while waiting for a pending request to complete"
+)
+
+// defaultBacklogTimeout is how long requests wait in the backlog if no
+// timeout is configured.
+const defaultBacklogTimeout = 60 * time.Second
+
+// ThrottleOpts configure request throttling.
+type ThrottleOpts struct {
+	// Limit is the number of requests processed concurrently.
+	Limit int
+
+	// BacklogLimit is the number of requests that may wait for a slot once
+	// the limit is reached. Requests beyond the backlog are rejected
+	// immediately.
+	BacklogLimit int
+
+	// BacklogTimeout is how long a request may wait in the backlog. It
+	// defaults to 60 seconds.
+	BacklogTimeout time.Duration
+
+	// RetryAfter is returned to rejected callers in the Retry-After header.
+	RetryAfter time.Duration
+
+	// ExemptPaths are never throttled, e.g. health probes.
+	ExemptPaths []string
+}
+
+// Throttle limits the number of requests processed concurrently. Requests
+// over the limit are rejected with a service unavailable problem.
+func Throttle(limit int) func(next http.Handler) http.Handler {
+	return ThrottleWithOpts(ThrottleOpts{Limit: limit})
+}
+
+// ThrottleWithOpts limits the number of requests processed concurrently,
+// holding a bounded backlog of pending requests. Rejected requests receive a
+// service unavailable problem with a Retry-After header, and requests canceled
+// while waiting in the backlog a too many requests problem.
+func ThrottleWithOpts(opts ThrottleOpts) func(next http.Handler) http.Handler {
+	if opts.Limit < 1 {
+		panic("middleware: Throttle expects limit > 0")
+	}
+	if opts.BacklogLimit < 0 {
+		panic("middleware: Throttle expects backlog limit >= 0")
+	}
+	tokens := make(chan struct{}, opts.Limit)
+	backlog := make(chan struct{}, opts.Limit+opts.BacklogLimit)
+	if opts.BacklogTimeout <= 0 {
+		opts.BacklogTimeout = defaultBacklogTimeout
+	}
+	retryAfter := ""
+	if opts.RetryAfter > 0 {
+		retryAfter = seconds(opts.RetryAfter)
+	}
+	reject := func(w http.ResponseWriter, r *http.Request, status int, detail string) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		problem.Error(w, r, status, detail)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if generics.Contains(opts.ExemptPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			select {
+			case backlog <- struct{}{}:
+			default:
+				reject(w, r, http.StatusServiceUnavailable, errCapacityExceeded)
+				return
+			}
+			defer func() { <-backlog }()
+
+			// Take a slot immediately if one is free, otherwise wait in the
+			// backlog.
+			select {
+			case tokens <- struct{}{}:
+			default:
+				if opts.BacklogLimit == 0 {
+					reject(w, r, http.StatusServiceUnavailable, errCapacityExceeded)
+					return
+				}
+				timer := time.NewTimer(opts.BacklogTimeout)
+				defer timer.Stop()
+				select {
+				case tokens <- struct{}{}:
+				case <-timer.C:
+					reject(w, r, http.StatusServiceUnavailable, errBacklogTimeout)
+					return
+				case <-r.Context().Done():
+					reject(w, r, http.StatusTooManyRequests, errBacklogCanceled)
+					return
+				}
+			}
+			defer func() { <-tokens }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
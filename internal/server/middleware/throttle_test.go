@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestThrottleWithOpts(t *testing.T) {
+	type arguments struct {
+		opts ThrottleOpts
+		path string
+		// wait is how long the request waits before it is canceled.
+		wait time.Duration
+	}
+	type want struct {
+		status     int
+		retryAfter string
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"Rejected": {
+			reason: "A request over the limit without a backlog should be rejected immediately.",
+			args: arguments{
+				opts: ThrottleOpts{Limit: 1, RetryAfter: 1500 * time.Millisecond},
+				path: "/v1/demo",
+			},
+			want: want{
+				status:     http.StatusServiceUnavailable,
+				retryAfter: "2",
+			},
+		},
+		"BacklogTimeout": {
+			reason: "A request waiting in the backlog should be rejected when the timeout expires.",
+			args: arguments{
+				opts: ThrottleOpts{Limit: 1, BacklogLimit: 1, BacklogTimeout: 10 * time.Millisecond},
+				path: "/v1/demo",
+			},
+			want: want{
+				status: http.StatusServiceUnavailable,
+			},
+		},
+		"BacklogCanceled": {
+			reason: "A request canceled while waiting in the backlog should be rejected as too many requests.",
+			args: arguments{
+				opts: ThrottleOpts{Limit: 1, BacklogLimit: 1, BacklogTimeout: time.Minute},
+				path: "/v1/demo",
+				wait: 10 * time.Millisecond,
+			},
+			want: want{
+				status: http.StatusTooManyRequests,
+			},
+		},
+		"DefaultBacklogTimeout": {
+			reason: "A request should wait in the backlog if no backlog timeout is configured, rather than be rejected immediately.",
+			args: arguments{
+				opts: ThrottleOpts{Limit: 1, BacklogLimit: 1},
+				path: "/v1/demo",
+				wait: 10 * time.Millisecond,
+			},
+			want: want{
+				status: http.StatusTooManyRequests,
+			},
+		},
+		"Exempt": {
+			reason: "A request to an exempt path should never be throttled.",
+			args: arguments{
+				opts: ThrottleOpts{Limit: 1, ExemptPaths: []string{"/livez"}},
+				path: "/livez",
+			},
+			want: want{
+				status: http.StatusOK,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			block := true
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if block {
+					block = false
+					close(started)
+					<-release
+				}
+			})
+			h := ThrottleWithOpts(tc.args.opts)(next)
+
+			// Occupy the only slot.
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				req, _ := http.NewRequestWithContext(context.Background(), "GET", "/v1/demo", nil)
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			}()
+			<-started
+
+			ctx := context.Background()
+			if tc.args.wait > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.args.wait)
+				defer cancel()
+			}
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequestWithContext(ctx, "GET", tc.args.path, nil)
+			h.ServeHTTP(rr, req)
+			close(release)
+			<-done
+
+			if diff := cmp.Diff(tc.want.status, rr.Code); diff != "" {
+				t.Errorf("\n%s\nThrottleWithOpts(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.retryAfter, rr.Header().Get("Retry-After")); diff != "" {
+				t.Errorf("\n%s\nThrottleWithOpts(...): -want Retry-After, +got Retry-After:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
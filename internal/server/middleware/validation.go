@@ -1,42 +1,84 @@
 package middleware
 
 import (
-	"context"
-	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/getkin/kin-openapi/routers"
 	"github.com/getkin/kin-openapi/routers/gorillamux"
-	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
 )
 
 // Copy of middleware from go-chi customized for supporting custom return values
 // for OCI specification errors
 
+// Error formats used to reject invalid requests.
+const (
+	// FormatProblem rejects requests with RFC 7807 problem details.
+	FormatProblem = "problem"
+	// FormatOCI rejects requests with OCI distribution spec errors.
+	FormatOCI = "oci"
+)
+
 // Options to customize request validation, openapi3filter specified options
 // will be passed through.
 type Options struct {
 	Options openapi3filter.Options
+
+	// Format selects how invalid requests are rejected by
+	// RequestValidatorWithOptions. Defaults to FormatProblem.
+	Format string
+
+	// OCI customizes the errors returned in the OCI format.
+	OCI OCIOptions
+}
+
+// RequestValidatorWithOptions creates middleware to validate requests against
+// the supplied spec. Invalid requests are rejected in the format selected by
+// the options, which allows each route group to choose its error format.
+func RequestValidatorWithOptions(swagger *openapi3.T, options *Options) func(next http.Handler) http.Handler {
+	if options != nil && options.Format == FormatOCI {
+		return OCIRequestValidatorWithOptions(swagger, options)
+	}
+	router, err := gorillamux.NewRouter(swagger)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := findAndValidateRequest(r, router, options); err != nil {
+				problem.Write(w, r, problem.FromValidationError(err))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 // OCIRequestValidatorWithOptions Creates middleware to validate request by
-// swagger spec. This middleware is good for net/http either since go-chi is
-// 100% compatible with net/http.
+// swagger spec. Invalid requests are rejected with errors in the format
+// defined by the OCI distribution spec. This middleware is good for net/http
+// either since go-chi is 100% compatible with net/http.
 func OCIRequestValidatorWithOptions(swagger *openapi3.T, options *Options) func(next http.Handler) http.Handler {
 	router, err := gorillamux.NewRouter(swagger)
 	if err != nil {
 		panic(err)
 	}
+	oci := OCIOptions{}
+	if options != nil {
+		oci = options.OCI
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 			// validate request
-			if statusCode, err := validateRequest(r, router, options); err != nil {
-				http.Error(w, err.Error(), statusCode)
+			if err := findAndValidateRequest(r, router, options); err != nil {
+				status, errs := oci.errorsFromValidationError(err)
+				WriteOCIErrors(w, status, errs...)
 				return
 			}
 
@@ -46,14 +88,14 @@ func OCIRequestValidatorWithOptions(swagger *openapi3.T, options *Options) func(
 	}
 }
 
-func validateRequest(r *http.Request, router routers.Router, options *Options) (int, error) {
-	// Find route
+// findAndValidateRequest finds the route for a request and validates the
+// request against it.
+func findAndValidateRequest(r *http.Request, router routers.Router, options *Options) error {
 	route, pathParams, err := router.FindRoute(r)
 	if err != nil {
-		return http.StatusBadRequest, err // We failed to find a matching route for the request.
+		return err
 	}
 
-	// Validate request
 	requestValidationInput := &openapi3filter.RequestValidationInput{
 		Request:    r,
 		PathParams: pathParams,
@@ -64,31 +106,5 @@ func validateRequest(r *http.Request, router routers.Router, options *Options) (
 		requestValidationInput.Options = &options.Options
 	}
 
-	if err := openapi3filter.ValidateRequest(context.Background(), requestValidationInput); err != nil {
-		switch e := errors.Cause(err).(type) { //nolint
-		case *openapi3filter.RequestError:
-			return getOCIResponseFromError(e)
-		case *openapi3filter.SecurityRequirementsError:
-			return http.StatusUnauthorized, err
-		default:
-			// This should never happen today, but if our upstream code changes,
-			// we don't want to crash the server, so handle the unexpected
-			// error.
-			return http.StatusInternalServerError, fmt.Errorf("error validating route: %w", err)
-		}
-	}
-
-	return http.StatusOK, nil
-}
-
-func getOCIResponseFromError(err *openapi3filter.RequestError) (int, error) {
-	switch e := err.Err.(type) { //nolint
-	case *openapi3.SchemaError:
-		return http.StatusNotFound, fmt.Errorf(e.Error())
-	case *openapi3filter.ParseError:
-		return http.StatusBadRequest, nil
-	default:
-		errorLines := strings.Split(err.Error(), "\n")
-		return http.StatusBadRequest, fmt.Errorf(errorLines[0])
-	}
+	return openapi3filter.ValidateRequest(r.Context(), requestValidationInput)
 }
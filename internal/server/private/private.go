@@ -3,23 +3,29 @@ package private
 import (
 	"fmt"
 	"net/http"
-	"time"
 
-	oapimiddleware "github.com/deepmap/oapi-codegen/pkg/chi-middleware"
 	oapifilter "github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/go-chi/chi/v5"
 	chimid "github.com/go-chi/chi/v5/middleware"
 
 	"github.com/upbound/build-submodule-demo/internal"
 	healthapi "github.com/upbound/build-submodule-demo/internal/api/health"
+	"github.com/upbound/build-submodule-demo/internal/log"
+	"github.com/upbound/build-submodule-demo/internal/reporting"
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
 	"github.com/upbound/build-submodule-demo/internal/server/health"
+	"github.com/upbound/build-submodule-demo/internal/server/middleware"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+	"github.com/upbound/build-submodule-demo/internal/server/spec"
 )
 
-// Server is a private API server.
-func Server(opts internal.ServiceOptions) (*http.Server, error) {
-	r := chi.NewRouter()
-	r.Use(chimid.RedirectSlashes)
-	r.Use(chimid.Compress(5))
+// Server is a private API server. Its requests take precedence over API
+// traffic in the supplied adaptive limiter if it is not nil.
+func Server(opts internal.ServiceOptions, limiter *middleware.AdaptiveLimiter) (*http.Server, error) {
+	proxies, err := forwarded.ParseCIDRs(opts.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
 
 	// Validate health requests against OpenAPIv3 spec.
 	healthSwagger, err := healthapi.GetSwagger()
@@ -28,21 +34,68 @@ func Server(opts internal.ServiceOptions) (*http.Server, error) {
 	}
 	healthSwagger.Servers = nil
 
+	limits, err := middleware.NewRouteLimits(healthSwagger,
+		middleware.RouteLimitsWithMaxBodyBytes(opts.PrivateTimeouts.MaxBodyBytes),
+		middleware.RouteLimitsWithTimeout(opts.PrivateTimeouts.RequestTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	reporter, err := reporting.New(opts.ErrorReportingOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	r := chi.NewRouter()
+	r.NotFound(problem.NotFound)
+	r.MethodNotAllowed(problem.MethodNotAllowed)
+	r.Use(middleware.RequestID(opts.RequestIDHeader, opts.Log))
+	r.Use(middleware.Forwarded(proxies))
+	r.Use(chimid.RequestLogger(log.NewAccessFormatter(opts.Log, opts.AccessLogOptions)))
+	r.Use(middleware.NewRecoverer(
+		middleware.RecovererWithLogger(opts.Log),
+		middleware.RecovererWithReporter(reporter),
+		middleware.RecovererWithRedactParams(opts.AccessLogRedactParams...),
+	).Recover)
+	r.Use(chimid.RedirectSlashes)
+	if limiter != nil {
+		r.Use(limiter.Prioritized)
+	}
+	if opts.EnableGZip {
+		r.Use(middleware.NewCompressor(
+			middleware.CompressorWithEncodings(opts.CompressionEncodings...),
+			middleware.CompressorWithMinSize(opts.CompressionMinSize),
+			middleware.CompressorWithContentTypes(opts.CompressionContentTypes...),
+		).Handler)
+	}
+	r.Use(limits.Handler)
+
+	if opts.OpenAPISpec {
+		sh, err := spec.New(healthSwagger, spec.OptsFromOptions(opts.OpenAPIOptions)...)
+		if err != nil {
+			return nil, err
+		}
+		sh.Mount(r)
+	}
+
 	// Override authentication because validator handles incorrectly.
-	healthValidOpts := &oapimiddleware.Options{}
+	healthValidOpts := &middleware.Options{}
 	healthValidOpts.Options.AuthenticationFunc = oapifilter.NoopAuthenticationFunc
 
 	r.Group(func(r chi.Router) {
-		r.Use(oapimiddleware.OapiRequestValidatorWithOptions(healthSwagger, healthValidOpts))
+		r.Use(middleware.RequestValidatorWithOptions(healthSwagger, healthValidOpts))
+		r.Use(middleware.ResponseValidatorWithOptions(healthSwagger, middleware.ResponseValidationMode(opts.ResponseValidation, opts.DevMode), opts.Log, healthValidOpts))
 		handlers := health.New(health.WithLogger(opts.Log))
-		healthapi.HandlerFromMux(handlers, r)
+		healthapi.HandlerFromMux(handlers.Handler(healthSwagger), r)
 	})
 
 	return &http.Server{
 		Handler:           r,
 		Addr:              fmt.Sprintf(":%d", opts.PrivatePort),
-		ReadTimeout:       5 * time.Second,
-		ReadHeaderTimeout: 5 * time.Second,
-		WriteTimeout:      10 * time.Second,
+		ReadTimeout:       opts.PrivateTimeouts.ReadTimeout,
+		ReadHeaderTimeout: opts.PrivateTimeouts.ReadHeaderTimeout,
+		WriteTimeout:      opts.PrivateTimeouts.WriteTimeout,
+		IdleTimeout:       opts.PrivateTimeouts.IdleTimeout,
 	}, nil
 }
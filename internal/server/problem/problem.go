@@ -0,0 +1,143 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	chimid "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/upbound/build-submodule-demo/internal/protocol"
+)
+
+// ContentType is the media type of RFC 7807 problem details.
+const ContentType = protocol.ProblemContentType
+
+// TypeDefault is the problem type used when no more specific type applies. As
+// per RFC 7807 the title of such problems is the HTTP status text.
+const TypeDefault = "about:blank"
+
+// A Problem describes an error as RFC 7807 problem details.
+type Problem = protocol.Problem
+
+// A FieldError describes why a single field of a request is invalid.
+type FieldError = protocol.FieldError
+
+// New constructs a problem with the supplied status and detail.
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   TypeDefault,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Write renders the problem for the supplied request. The instance and request
+// ID are filled in from the request if not already set.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.RequestID == "" {
+		p.RequestID = chimid.GetReqID(r.Context())
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// Error renders a problem with the supplied status and detail.
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	Write(w, r, New(status, detail))
+}
+
+// NotFound renders a not found problem. It may be used as a router's not found
+// handler.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, http.StatusNotFound, "no route matches the requested path")
+}
+
+// MethodNotAllowed renders a method not allowed problem. It may be used as a
+// router's method not allowed handler.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, http.StatusMethodNotAllowed, "the requested method is not supported for this path")
+}
+
+// FromValidationError constructs a problem from an OpenAPI request validation
+// error, including field-level errors where they can be determined.
+func FromValidationError(err error) *Problem {
+	var (
+		reqErr   *openapi3filter.RequestError
+		secErr   *openapi3filter.SecurityRequirementsError
+		multiErr openapi3.MultiError
+		sizeErr  *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &sizeErr):
+		return New(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", sizeErr.Limit))
+	case errors.Is(err, routers.ErrPathNotFound):
+		return New(http.StatusNotFound, err.Error())
+	case errors.Is(err, routers.ErrMethodNotAllowed):
+		return New(http.StatusMethodNotAllowed, err.Error())
+	case errors.As(err, &secErr):
+		return New(http.StatusUnauthorized, secErr.Error())
+	case errors.As(err, &multiErr), errors.As(err, &reqErr):
+		p := New(http.StatusBadRequest, "request does not match the API specification")
+		p.Errors = FieldErrors(err)
+		return p
+	default:
+		return New(http.StatusInternalServerError, "failed to validate request")
+	}
+}
+
+// FieldErrors extracts field-level errors from kin-openapi validation errors.
+func FieldErrors(err error) []FieldError {
+	var out []FieldError
+	var walk func(in, field string, err error)
+	walk = func(in, field string, err error) {
+		switch e := err.(type) { //nolint:errorlint // we walk the error tree ourselves.
+		case openapi3.MultiError:
+			for _, me := range e {
+				walk(in, field, me)
+			}
+		case *openapi3filter.RequestError:
+			switch {
+			case e.Parameter != nil:
+				in, field = e.Parameter.In, e.Parameter.Name
+			case e.RequestBody != nil:
+				in = "body"
+			}
+			if e.Err == nil {
+				out = append(out, FieldError{In: in, Field: field, Reason: e.Reason})
+				return
+			}
+			walk(in, field, e.Err)
+		case *openapi3.SchemaError:
+			if p := e.JSONPointer(); len(p) > 0 {
+				field = "/" + strings.Join(p, "/")
+			}
+			reason := e.Reason
+			if reason == "" {
+				reason = "doesn't match schema " + e.SchemaField
+			}
+			out = append(out, FieldError{In: in, Field: field, Reason: reason})
+		case *openapi3filter.ParseError:
+			out = append(out, FieldError{In: in, Field: field, Reason: e.Error()})
+		default:
+			out = append(out, FieldError{In: in, Field: field, Reason: firstLine(err.Error())})
+		}
+	}
+	walk("", "", err)
+	return out
+}
+
+func firstLine(s string) string {
+	return strings.SplitN(s, "\n", 2)[0]
+}
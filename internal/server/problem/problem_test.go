@@ -0,0 +1,139 @@
+package problem
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/google/go-cmp/cmp"
+)
+
+const spec = `
+openapi: 3.0.0
+info:
+  title: Test
+  version: '1.0'
+paths:
+  /things:
+    post:
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            type: integer
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name:
+                  type: string
+                size:
+                  type: integer
+                  minimum: 1
+      responses:
+        '200':
+          description: OK
+`
+
+func TestFromValidationError(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(spec))
+	if err != nil {
+		t.Fatalf("LoadFromData(...): unexpected error: %v", err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("NewRouter(...): unexpected error: %v", err)
+	}
+	type arguments struct {
+		method string
+		target string
+		body   string
+	}
+	type want struct {
+		status int
+		errors []FieldError
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"PathNotFound": {
+			reason: "A request for an unknown path should be a not found problem.",
+			args: arguments{
+				method: http.MethodGet,
+				target: "/unknown",
+			},
+			want: want{
+				status: http.StatusNotFound,
+			},
+		},
+		"MethodNotAllowed": {
+			reason: "A request with an unsupported method should be a method not allowed problem.",
+			args: arguments{
+				method: http.MethodGet,
+				target: "/things",
+			},
+			want: want{
+				status: http.StatusMethodNotAllowed,
+			},
+		},
+		"InvalidParameter": {
+			reason: "An invalid query parameter should be reported as a field error.",
+			args: arguments{
+				method: http.MethodPost,
+				target: "/things?limit=abc",
+				body:   `{"name":"a"}`,
+			},
+			want: want{
+				status: http.StatusBadRequest,
+				errors: []FieldError{{In: "query", Field: "limit", Reason: `value abc: an invalid integer: invalid syntax`}},
+			},
+		},
+		"InvalidBody": {
+			reason: "An invalid body field should be reported with its JSON pointer.",
+			args: arguments{
+				method: http.MethodPost,
+				target: "/things",
+				body:   `{"name":"a","size":0}`,
+			},
+			want: want{
+				status: http.StatusBadRequest,
+				errors: []FieldError{{In: "body", Field: "/size", Reason: "number must be at least 1"}},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.args.method, tc.args.target, strings.NewReader(tc.args.body))
+			req.Header.Set("Content-Type", "application/json")
+			err := func() error {
+				route, params, err := router.FindRoute(req)
+				if err != nil {
+					return err
+				}
+				return openapi3filter.ValidateRequest(context.Background(), &openapi3filter.RequestValidationInput{
+					Request:    req,
+					PathParams: params,
+					Route:      route,
+				})
+			}()
+			p := FromValidationError(err)
+			if diff := cmp.Diff(tc.want.status, p.Status); diff != "" {
+				t.Errorf("\n%s\nFromValidationError(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.errors, p.Errors); diff != "" {
+				t.Errorf("\n%s\nFromValidationError(...): -want errors, +got errors:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package spec serves the OpenAPI specs of servers, and interactive
+// documentation generated from them.
+package spec
+
+import (
+	"bytes"
+	_ "embed" // Required for go:embed.
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/upbound/build-submodule-demo/internal"
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errMarshalSpec = "failed to marshal OpenAPI spec"
+	errRenderSpec  = "failed to render OpenAPI spec"
+)
+
+// Paths the spec and documentation are served at.
+const (
+	PathJSON = "/openapi.json"
+	PathYAML = "/openapi.yaml"
+	PathDocs = "/docs"
+)
+
+// DefaultDocsScriptURL is the URL of the Redoc script used by the
+// documentation page by default.
+const DefaultDocsScriptURL = "https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js"
+
+//go:embed docs.html
+var docsHTML string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsHTML))
+
+// Handler serves an OpenAPI spec as JSON and YAML. The servers of the spec
+// are replaced by the client-facing origin and path prefix of each request,
+// as determined by Forwarded, so that clients can call the API they
+// discovered it from.
+type Handler struct {
+	spec   map[string]any
+	title  string
+	prefix string
+	docs   bool
+	script string
+}
+
+// Opt modifies a spec handler.
+type Opt func(h *Handler)
+
+// WithPathPrefix sets the path prefix used in the servers of the spec when a
+// trusted proxy does not supply one with X-Forwarded-Prefix.
+func WithPathPrefix(p string) Opt {
+	return func(h *Handler) {
+		h.prefix = strings.TrimSuffix(p, "/")
+	}
+}
+
+// WithDocs enables the interactive documentation page, which loads Redoc from
+// the supplied script URL.
+func WithDocs(scriptURL string) Opt {
+	return func(h *Handler) {
+		h.docs = true
+		h.script = scriptURL
+	}
+}
+
+// OptsFromOptions returns the spec handler options configured by the
+// supplied options.
+func OptsFromOptions(o internal.OpenAPIOptions) []Opt {
+	if !o.OpenAPIDocs {
+		return nil
+	}
+	return []Opt{WithDocs(o.OpenAPIDocsScript)}
+}
+
+// New constructs a handler serving the supplied spec.
+func New(swagger *openapi3.T, opts ...Opt) (*Handler, error) {
+	b, err := json.Marshal(swagger)
+	if err != nil {
+		return nil, errors.Wrap(err, errMarshalSpec)
+	}
+	h := &Handler{script: DefaultDocsScriptURL}
+	if err := json.Unmarshal(b, &h.spec); err != nil {
+		return nil, errors.Wrap(err, errMarshalSpec)
+	}
+	if swagger.Info != nil {
+		h.title = swagger.Info.Title
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	return h, nil
+}
+
+// Mount adds the spec and, if enabled, documentation routes to a router.
+func (h *Handler) Mount(r chi.Router) {
+	r.Get(PathJSON, h.JSON)
+	r.Get(PathYAML, h.YAML)
+	if h.docs {
+		r.Get(PathDocs, h.Docs)
+	}
+}
+
+// JSON serves the spec as JSON.
+func (h *Handler) JSON(w http.ResponseWriter, r *http.Request) {
+	b, err := json.Marshal(h.forRequest(r))
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, errRenderSpec)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// YAML serves the spec as YAML.
+func (h *Handler) YAML(w http.ResponseWriter, r *http.Request) {
+	b, err := yaml.Marshal(h.forRequest(r))
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, errRenderSpec)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(b)
+}
+
+// Docs serves the interactive documentation page.
+func (h *Handler) Docs(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	err := docsTemplate.Execute(&buf, struct {
+		Title     string
+		SpecURL   string
+		ScriptURL string
+	}{
+		Title: h.title,
+		// The spec is referenced relative to the page so that it is found
+		// under any path prefix.
+		SpecURL:   strings.TrimPrefix(PathJSON, "/"),
+		ScriptURL: h.script,
+	})
+	if err != nil {
+		problem.Error(w, r, http.StatusInternalServerError, errRenderSpec)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+// forRequest returns the spec with its servers replaced by the client-facing
+// URL of the request.
+func (h *Handler) forRequest(r *http.Request) map[string]any {
+	fwd := forwarded.FromRequest(r)
+	prefix := fwd.Prefix
+	if prefix == "" {
+		prefix = h.prefix
+	}
+	s := make(map[string]any, len(h.spec)+1)
+	for k, v := range h.spec {
+		s[k] = v
+	}
+	s["servers"] = []any{map[string]any{"url": fwd.Scheme + "://" + fwd.Host + prefix}}
+	return s
+}
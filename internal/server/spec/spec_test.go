@@ -0,0 +1,108 @@
+package spec
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/yaml"
+
+	"github.com/upbound/build-submodule-demo/internal/server/forwarded"
+)
+
+func TestHandler(t *testing.T) {
+	swagger := &openapi3.T{
+		OpenAPI: "3.0.0",
+		Info:    &openapi3.Info{Title: "Demo", Version: "1"},
+		Servers: openapi3.Servers{{URL: "http://internal:8081"}},
+		Paths:   openapi3.Paths{},
+	}
+	type arguments struct {
+		path string
+		fwd  *forwarded.Info
+		opts []Opt
+	}
+	type want struct {
+		status  int
+		servers []any
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   want
+	}{
+		"JSON": {
+			reason: "The servers of the JSON spec should be the origin of the request.",
+			args:   arguments{path: PathJSON},
+			want:   want{status: http.StatusOK, servers: []any{map[string]any{"url": "http://example.com"}}},
+		},
+		"YAML": {
+			reason: "The servers of the YAML spec should be the origin of the request.",
+			args:   arguments{path: PathYAML},
+			want:   want{status: http.StatusOK, servers: []any{map[string]any{"url": "http://example.com"}}},
+		},
+		"Forwarded": {
+			reason: "The servers of the spec should be the client-facing origin and prefix of the request.",
+			args: arguments{
+				path: PathJSON,
+				fwd:  &forwarded.Info{Scheme: "https", Host: "api.example.com", Prefix: "/demo"},
+			},
+			want: want{status: http.StatusOK, servers: []any{map[string]any{"url": "https://api.example.com/demo"}}},
+		},
+		"PathPrefix": {
+			reason: "The configured path prefix should be used when none is forwarded.",
+			args:   arguments{path: PathJSON, opts: []Opt{WithPathPrefix("/api/")}},
+			want:   want{status: http.StatusOK, servers: []any{map[string]any{"url": "http://example.com/api"}}},
+		},
+		"DocsDisabled": {
+			reason: "The documentation page should not be served unless enabled.",
+			args:   arguments{path: PathDocs},
+			want:   want{status: http.StatusNotFound},
+		},
+		"Docs": {
+			reason: "The documentation page should be served when enabled.",
+			args:   arguments{path: PathDocs, opts: []Opt{WithDocs("https://example.com/redoc.js")}},
+			want:   want{status: http.StatusOK},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			h, err := New(swagger, tc.args.opts...)
+			if err != nil {
+				t.Fatalf("New(...): %v", err)
+			}
+			r := chi.NewRouter()
+			h.Mount(r)
+			req := httptest.NewRequest(http.MethodGet, tc.args.path, nil)
+			if tc.args.fwd != nil {
+				req = req.WithContext(forwarded.NewContext(req.Context(), *tc.args.fwd))
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			got := want{status: rr.Code}
+			var s map[string]any
+			switch tc.args.path {
+			case PathJSON:
+				_ = json.Unmarshal(rr.Body.Bytes(), &s)
+			case PathYAML:
+				_ = yaml.Unmarshal(rr.Body.Bytes(), &s)
+			case PathDocs:
+				if rr.Code == http.StatusOK && !strings.Contains(rr.Body.String(), `src="https://example.com/redoc.js"`) {
+					t.Errorf("\n%s\nDocs(...): page does not load the configured script", tc.reason)
+				}
+			}
+			if s != nil {
+				got.servers, _ = s["servers"].([]any)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nHandler(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Package strict provides middleware and error handling shared by handlers
+// that implement an oapi-codegen strict server interface.
+package strict
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+	chimid "github.com/go-chi/chi/v5/middleware"
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal/client/auth"
+	"github.com/upbound/build-submodule-demo/internal/log"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+const (
+	errOperationFailed = "operation failed"
+	errInvalidRequest  = "invalid request"
+	errInternal        = "the server failed to handle the request"
+	errUnauthenticated = "authentication is required"
+)
+
+// A HandlerFunc handles a decoded request of an operation. It has the same
+// shape as the StrictHandlerFunc of each generated package.
+type HandlerFunc func(ctx context.Context, w http.ResponseWriter, r *http.Request, request any) (any, error)
+
+// A MiddlewareFunc wraps the handler of the supplied operation. It has the
+// same shape as the StrictMiddlewareFunc of each generated package.
+type MiddlewareFunc func(f HandlerFunc, operationID string) HandlerFunc
+
+// Middlewares adapts the supplied middleware to the StrictMiddlewareFunc type
+// M of a generated package, e.g.
+//
+//	strict.Middlewares[api.StrictMiddlewareFunc](strict.Authenticated(s), strict.Logging(l))
+//
+// Generated handlers wrap each middleware around the ones before it, so the
+// last middleware is the outermost. Logging should be last so that it sees
+// requests rejected by other middleware.
+func Middlewares[M ~func(H, string) H, H ~func(context.Context, http.ResponseWriter, *http.Request, any) (any, error)](mws ...MiddlewareFunc) []M {
+	out := make([]M, len(mws))
+	for i, mw := range mws {
+		mw := mw
+		out[i] = func(f H, operationID string) H {
+			return H(mw(HandlerFunc(f), operationID))
+		}
+	}
+	return out
+}
+
+// Logging returns middleware that makes a logger scoped to the operation
+// available to handlers via log.FromContext. It extends the request's logger
+// if one is in the context, and otherwise the supplied logger scoped to the
+// request. Failed operations are logged at debug level.
+func Logging(l logging.Logger) MiddlewareFunc {
+	return func(f HandlerFunc, operationID string) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, request any) (any, error) {
+			rl, ok := log.FromContext(ctx)
+			if !ok {
+				rl = l.WithValues("requestID", chimid.GetReqID(ctx))
+			}
+			ol := rl.WithValues("operation", operationID)
+			start := time.Now()
+			res, err := f(log.NewContext(ctx, ol), w, r, request)
+			if err != nil {
+				ol.Debug(errOperationFailed, "error", err, "duration", time.Since(start))
+			}
+			return res, err
+		}
+	}
+}
+
+// Authenticated returns middleware that rejects requests without an
+// authenticated user or robot in their context, if the operation has security
+// requirements in the supplied spec. Operations that list an empty security
+// requirement allow anonymous requests.
+func Authenticated(swagger *openapi3.T) MiddlewareFunc {
+	required := map[string]bool{}
+	for _, item := range swagger.Paths {
+		for _, op := range item.Operations() {
+			sr := swagger.Security
+			if op.Security != nil {
+				sr = *op.Security
+			}
+			required[normalize(op.OperationID)] = securityRequired(sr)
+		}
+	}
+	return func(f HandlerFunc, operationID string) HandlerFunc {
+		if !required[normalize(operationID)] {
+			return f
+		}
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, request any) (any, error) {
+			if !authenticated(ctx) {
+				return nil, problem.New(http.StatusUnauthorized, errUnauthenticated)
+			}
+			return f(ctx, w, r, request)
+		}
+	}
+}
+
+// An ErrorHandler renders errors returned by strict handlers as problem
+// details.
+type ErrorHandler struct {
+	log logging.Logger
+}
+
+// ErrorHandlerOpt modifies an error handler.
+type ErrorHandlerOpt func(e *ErrorHandler)
+
+// ErrorHandlerWithLogger sets the logger of an error handler.
+func ErrorHandlerWithLogger(l logging.Logger) ErrorHandlerOpt {
+	return func(e *ErrorHandler) {
+		e.log = l
+	}
+}
+
+// NewErrorHandler constructs a new error handler.
+func NewErrorHandler(opts ...ErrorHandlerOpt) *ErrorHandler {
+	e := &ErrorHandler{log: logging.NewNopLogger()}
+	for _, o := range opts {
+		o(e)
+	}
+	return e
+}
+
+// Request renders an error decoding a request as a bad request. The error is
+// logged rather than rendered, as it may include details of the server's
+// implementation. It may be used as the RequestErrorHandlerFunc of a strict
+// server.
+func (e *ErrorHandler) Request(w http.ResponseWriter, r *http.Request, err error) {
+	e.log.Debug(errInvalidRequest, "error", err, "requestID", chimid.GetReqID(r.Context()))
+	problem.Error(w, r, http.StatusBadRequest, errInvalidRequest)
+}
+
+// Response renders an error returned by a handler. Problems are rendered as
+// is; other errors are logged and rendered as an internal server error without
+// their details. It may be used as the ResponseErrorHandlerFunc of a strict
+// server.
+func (e *ErrorHandler) Response(w http.ResponseWriter, r *http.Request, err error) {
+	var p *problem.Problem
+	if errors.As(err, &p) {
+		problem.Write(w, r, p)
+		return
+	}
+	e.log.Info(errOperationFailed, "error", err, "requestID", chimid.GetReqID(r.Context()))
+	problem.Error(w, r, http.StatusInternalServerError, errInternal)
+}
+
+// securityRequired reports whether the supplied requirements admit anonymous
+// requests.
+func securityRequired(sr openapi3.SecurityRequirements) bool {
+	for _, req := range sr {
+		if len(req) == 0 {
+			return false
+		}
+	}
+	return len(sr) > 0
+}
+
+// authenticated reports whether the context carries a user or robot.
+func authenticated(ctx context.Context) bool {
+	if _, ok := auth.UserIDFromContext(ctx); ok {
+		return true
+	}
+	_, ok := auth.RobotIDFromContext(ctx)
+	return ok
+}
+
+// normalize returns an operation ID without case or punctuation, such that a
+// spec operation ID like get-v1-demo matches its generated name GetV1Demo.
+func normalize(id string) string {
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, id))
+}
@@ -0,0 +1,167 @@
+package strict
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/upbound/build-submodule-demo/internal/client/auth"
+	"github.com/upbound/build-submodule-demo/internal/log"
+	"github.com/upbound/build-submodule-demo/internal/server/problem"
+)
+
+// valuesLogger records the values it was scoped with.
+type valuesLogger struct {
+	logging.Logger
+	values []any
+}
+
+func (l valuesLogger) WithValues(kv ...any) logging.Logger {
+	return valuesLogger{Logger: l.Logger, values: append(append([]any{}, l.values...), kv...)}
+}
+
+func TestLogging(t *testing.T) {
+	base := valuesLogger{Logger: logging.NewNopLogger(), values: []any{"base", true}}
+	cases := map[string]struct {
+		reason string
+		ctx    context.Context
+		want   []any
+	}{
+		"RequestLogger": {
+			reason: "The request's logger should be scoped to the operation.",
+			ctx:    log.NewContext(context.Background(), valuesLogger{Logger: logging.NewNopLogger(), values: []any{"requestID", "r1"}}),
+			want:   []any{"requestID", "r1", "operation", "GetThing"},
+		},
+		"NoRequestLogger": {
+			reason: "The supplied logger should be scoped to the request and operation if the request has no logger.",
+			ctx:    context.Background(),
+			want:   []any{"base", true, "requestID", "", "operation", "GetThing"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []any
+			f := Logging(base)(func(ctx context.Context, _ http.ResponseWriter, _ *http.Request, _ any) (any, error) {
+				l, _ := log.FromContext(ctx)
+				got = l.(valuesLogger).values
+				return nil, nil
+			}, "GetThing")
+			_, _ = f(tc.ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nLogging(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAuthenticated(t *testing.T) {
+	secured := openapi3.SecurityRequirements{{"session": {}}}
+	anonymous := openapi3.SecurityRequirements{{}}
+	swagger := &openapi3.T{
+		Security: secured,
+		Paths: openapi3.Paths{
+			"/secure":    {Get: &openapi3.Operation{OperationID: "get-secure"}},
+			"/anonymous": {Get: &openapi3.Operation{OperationID: "get-anonymous", Security: &anonymous}},
+		},
+	}
+	type arguments struct {
+		operationID string
+		user        bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   arguments
+		want   int
+	}{
+		"Unauthenticated": {
+			reason: "Operations with security requirements should reject anonymous requests.",
+			args:   arguments{operationID: "GetSecure"},
+			want:   http.StatusUnauthorized,
+		},
+		"Authenticated": {
+			reason: "Operations with security requirements should accept authenticated requests.",
+			args:   arguments{operationID: "GetSecure", user: true},
+			want:   http.StatusOK,
+		},
+		"Anonymous": {
+			reason: "Operations that allow anonymous requests should accept them.",
+			args:   arguments{operationID: "GetAnonymous"},
+			want:   http.StatusOK,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := Authenticated(swagger)(func(_ context.Context, _ http.ResponseWriter, _ *http.Request, _ any) (any, error) {
+				return http.StatusOK, nil
+			}, tc.args.operationID)
+			ctx := context.Background()
+			if tc.args.user {
+				ctx = context.WithValue(ctx, auth.UserKey, uint(1))
+			}
+			res, err := f(ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+			got, _ := res.(int)
+			var p *problem.Problem
+			if errors.As(err, &p) {
+				got = p.Status
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nAuthenticated(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerRequest(t *testing.T) {
+	rr := httptest.NewRecorder()
+	NewErrorHandler().Request(rr, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("json: cannot unmarshal number into Go struct field Thing.name"))
+	p := problem.Problem{}
+	_ = json.Unmarshal(rr.Body.Bytes(), &p)
+	if diff := cmp.Diff(http.StatusBadRequest, rr.Code); diff != "" {
+		t.Errorf("\nRequest(...): -want status, +got status:\n%s", diff)
+	}
+	if diff := cmp.Diff(errInvalidRequest, p.Detail); diff != "" {
+		t.Errorf("\nRequest(...): errors decoding requests should not be rendered: -want detail, +got detail:\n%s", diff)
+	}
+}
+
+func TestErrorHandlerResponse(t *testing.T) {
+	type want struct {
+		status int
+		detail string
+	}
+	cases := map[string]struct {
+		reason string
+		err    error
+		want   want
+	}{
+		"Problem": {
+			reason: "Problems returned by handlers should be rendered as is.",
+			err:    errors.Wrap(problem.New(http.StatusNotFound, "no such thing"), "lookup"),
+			want:   want{status: http.StatusNotFound, detail: "no such thing"},
+		},
+		"Error": {
+			reason: "Other errors should be rendered as internal server errors without their details.",
+			err:    errors.New("boom"),
+			want:   want{status: http.StatusInternalServerError, detail: errInternal},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			NewErrorHandler().Response(rr, httptest.NewRequest(http.MethodGet, "/", nil), tc.err)
+			p := problem.Problem{}
+			_ = json.Unmarshal(rr.Body.Bytes(), &p)
+			got := want{status: rr.Code, detail: p.Detail}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nResponse(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}